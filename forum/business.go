@@ -1,35 +1,104 @@
 package forum
 
 import (
+	"time"
+
 	"forum/forum/domain"
+	"forum/forum/webauthn"
 
 	"github.com/gofrs/uuid"
 )
 
 type Business interface {
-	Login(username, password string) (uuid.UUID, error)
-	Registration(username, password, email string) error
+	Login(username, password string, client domain.Client) (uuid.UUID, string, error)
+	CompleteTwoFactorLogin(challenge, code string, client domain.Client) (uuid.UUID, error)
+	SetUserRole(username string, role domain.Role) error
+	Registration(username, password, email string, client domain.Client) error
 	Session(sessionID string) (*domain.Session, error)
-	GetUserActivity(userID int) (domain.UserActivity, error)
-	Post(domain.Posts) error
-	GetAllPosts() ([]domain.Posts, error)
-	GetMyPosts(userId int) ([]domain.Posts, error)
-	DeletePost(postId int) error
+	GetSessions(userID int) ([]domain.Session, error)
+	RevokeSession(userID, id int) error
+	RevokeOtherSessions(userID int, sessionID string) error
+	Logout(sessionID string) error
+	LogoutEverywhere(sessionID string) error
+	GetUserActivity(userID int, req domain.PageRequest) (domain.UserActivity, domain.Page, error)
+	Post(actor *domain.Session, post domain.Posts) error
+	GetAllPosts(viewer *domain.Session, filter domain.PostFilter, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetMyPosts(userId int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	DeletePost(actor *domain.Session, postId int) error
 
 	GetPostByID(postId int) (domain.Posts, error)
-	AddComment(comment domain.Comments, userID, postID int) error
-	GetComments(postId int) ([]domain.Comments, error)
-	DeleteComment(comment_id int) error
+	AddComment(actor *domain.Session, comment domain.Comments) error
+	GetComments(viewer *domain.Session, postId int) ([]domain.Comments, error)
+	GetCommentByID(commentID int) (domain.Comments, error)
+	DeleteComment(actor *domain.Session, comment_id int) error
 	GetUserById(userId int) ([]domain.User, error)
-	LikePost(postID, userID, ownerID int, activity string, username string) error
-	DislikePost(postID, userID, ownerID int, activity string, username string) error
-	GetLikedPosts(userID int) ([]domain.Posts, error)
-	GetDislikedPosts(userID int) ([]domain.Posts, error)
-	GetPostsByCategories(categories []string) ([]domain.Posts, error)
-	LikeComment(commentID int, userID int, ownerID int, activity string, username string, postID int) error
-	DislikeComment(commentID int, userID int, ownerID int, activity string, username string, postID int) error
-	GetAllNotifications(ownerID int) ([]domain.Notification, error)
-	GetAllNotificationsComment(ownerID int) ([]domain.Notification_comments, error)
-	EditPost(postId int, post domain.Posts) error
-	EditComment(commentId int, comment domain.Comments) error
+	LikePost(actor *domain.Session, postID int, activity string) error
+	DislikePost(actor *domain.Session, postID int, activity string) error
+	GetLikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetDislikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetPostsByCategories(viewer *domain.Session, filter domain.PostFilter, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetCategories() ([]domain.Category, error)
+	CheckCategoryRules(actor *domain.Session, categories []domain.Category, action domain.Action) error
+	CreateCategory(actor *domain.Session, category domain.Category) error
+	UpdateCategory(actor *domain.Session, category domain.Category) error
+	ArchiveCategory(actor *domain.Session, id int, archived bool) error
+	MoveCategory(actor *domain.Session, id int, offset int) error
+	MergeCategory(actor *domain.Session, fromID, intoID int) error
+	GetPostsByTag(viewer *domain.Session, name string, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetTagCloud(viewer *domain.Session) ([]domain.Tag, error)
+	GetTags(actor *domain.Session) ([]domain.Tag, error)
+	SuggestTags(prefix string) ([]string, error)
+	BanTag(actor *domain.Session, name string, banned bool) error
+	MergeTags(actor *domain.Session, from, into string) error
+	Search(viewer *domain.Session, query domain.SearchQuery, req domain.PageRequest) ([]domain.SearchResult, domain.Page, error)
+	RebuildSearchIndex() error
+	SaveSearch(userID int, name, query string, notify, digest bool) error
+	GetSavedSearches(userID int) ([]domain.SavedSearch, error)
+	UpdateSavedSearch(userID, id int, notify, digest bool) error
+	DeleteSavedSearch(userID, id int) error
+	GetSearchAlerts(ownerID int, req domain.PageRequest) ([]domain.SearchAlert, domain.Page, error)
+	PreviewMarkdown(text string) string
+	LikeComment(actor *domain.Session, commentID int, activity string) error
+	DislikeComment(actor *domain.Session, commentID int, activity string) error
+	GetAllNotifications(ownerID int, req domain.PageRequest) ([]domain.Notification, domain.Page, error)
+	GetAllNotificationsComment(ownerID int, req domain.PageRequest) ([]domain.Notification_comments, domain.Page, error)
+	EditPost(actor *domain.Session, postId int, post domain.Posts) error
+	EditComment(actor *domain.Session, commentId int, comment domain.Comments) error
+	RequestPasswordReset(email string) error
+	CheckPasswordResetToken(token string) error
+	ResetPassword(token, password string) error
+	VerifyEmail(token string) error
+	ResendVerification(userID int) error
+	CheckPermission(session *domain.Session, action domain.Action) error
+	Authorize(actor *domain.Session, action domain.Action, ownerID int) error
+	GetTwoFactorStatus(userID int, username string) (domain.TwoFactorStatus, error)
+	BeginTwoFactorSetup(userID int) error
+	ConfirmTwoFactorSetup(userID int, code string) ([]string, error)
+	DisableTwoFactor(userID int, code string) error
+	RegenerateRecoveryCodes(userID int, code string) ([]string, error)
+	ResetTwoFactor(actor *domain.Session, username string) error
+	BeginPasskeyRegistration(userID int, username string) (webauthn.CreationOptions, error)
+	FinishPasskeyRegistration(userID int, name string, resp webauthn.AttestationResponse) error
+	BeginPasskeyLogin() (webauthn.RequestOptions, error)
+	FinishPasskeyLogin(resp webauthn.AssertionResponse, client domain.Client) (uuid.UUID, error)
+	GetPasskeys(userID int) ([]domain.Passkey, error)
+	RenamePasskey(userID, id int, name string) error
+	DeletePasskey(userID, id int) error
+	OAuthProviders() []domain.IdentityProvider
+	BeginOAuth(provider string, linkUserID int) (string, string, error)
+	FinishOAuth(provider, state, code string, client domain.Client, session *domain.Session) (uuid.UUID, string, error)
+	GetUserIdentities(userID int) ([]domain.UserIdentity, error)
+	UnlinkIdentity(userID, id int) error
+	Report(actor *domain.Session, postID, commentID int, reason domain.ReportReason, details string) error
+	GetModerationQueue(actor *domain.Session) ([]domain.ModerationItem, error)
+	Moderate(actor *domain.Session, postID, commentID int, action domain.ModerationAction, note string) error
+	SearchUsers(actor *domain.Session, query string) ([]domain.UserSummary, error)
+	ChangeUserRole(actor *domain.Session, userID int, role domain.Role) error
+	SuspendUser(actor *domain.Session, userID int, until time.Time) error
+	BanUser(actor *domain.Session, userID int, reason string) error
+	UnbanUser(actor *domain.Session, userID int) error
+	ShadowBanUser(actor *domain.Session, userID int, shadowBanned bool) error
+	ForcePasswordReset(actor *domain.Session, userID int) error
+	SignOutUser(actor *domain.Session, userID int) error
+	GetAuditLog(actor *domain.Session, filter domain.AuditFilter) ([]domain.AuditEntry, error)
 }
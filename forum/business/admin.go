@@ -0,0 +1,168 @@
+package business
+
+import (
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const adminSearchLimit = 100
+
+// SearchUsers lists the users whose name or email contains query for the
+// admin dashboard; an empty query lists the newest accounts.
+func (b *Business) SearchUsers(actor *domain.Session, query string) ([]domain.UserSummary, error) {
+	err := b.Authorize(actor, domain.ActionManageUsers, 0)
+	if err != nil {
+		return nil, err
+	}
+	return b.repo.SearchUsers(strings.TrimSpace(query), adminSearchLimit)
+}
+
+// managedUser checks that actor may manage the user with userID and returns
+// that user. Admins cannot sanction or demote themselves, so the forum is
+// never left without one by accident.
+func (b *Business) managedUser(actor *domain.Session, userID int) (domain.User, error) {
+	err := b.Authorize(actor, domain.ActionManageUsers, 0)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if actor.UserId == userID {
+		return domain.User{}, domain.ErrSelfAction
+	}
+	users, err := b.repo.GetUserById(userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if len(users) == 0 {
+		return domain.User{}, domain.ErrInvalidUser
+	}
+	return users[0], nil
+}
+
+func (b *Business) ChangeUserRole(actor *domain.Session, userID int, role domain.Role) error {
+	if !role.AtLeast(domain.RoleUser) {
+		return domain.ErrInvalidRole
+	}
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(actor, domain.AuditChangeRole, domain.TargetUser, userID, user, "new role: "+string(role))
+	if err != nil {
+		return err
+	}
+	return b.repo.SetUserRole(userID, role, entry)
+}
+
+// SuspendUser keeps a user from writing until the given time. The zero time
+// lifts the suspension.
+func (b *Business) SuspendUser(actor *domain.Session, userID int, until time.Time) error {
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	var entry domain.AuditEntry
+	if until.IsZero() {
+		entry, err = auditEntry(actor, domain.AuditUnsuspend, domain.TargetUser, userID, user, "")
+	} else {
+		entry, err = auditEntry(actor, domain.AuditSuspend, domain.TargetUser, userID, user, "until "+until.Format("2006-01-02 15:04"))
+	}
+	if err != nil {
+		return err
+	}
+	return b.repo.SetUserSuspension(userID, until, entry)
+}
+
+// BanUser refuses the user any further sign-in and ends their sessions.
+func (b *Business) BanUser(actor *domain.Session, userID int, reason string) error {
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+	entry, err := auditEntry(actor, domain.AuditBan, domain.TargetUser, userID, user, reason)
+	if err != nil {
+		return err
+	}
+	err = b.repo.SetUserBan(userID, true, reason, entry)
+	if err != nil {
+		return err
+	}
+	return b.repo.InvalidateSessions(userID, nil)
+}
+
+// ShadowBanUser makes the user's new posts and comments visible to
+// themselves only and stops counting their reactions, or lifts that.
+func (b *Business) ShadowBanUser(actor *domain.Session, userID int, shadowBanned bool) error {
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	action := domain.AuditShadowBan
+	if !shadowBanned {
+		action = domain.AuditUnshadowBan
+	}
+	entry, err := auditEntry(actor, action, domain.TargetUser, userID, user, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.SetUserShadowBan(userID, shadowBanned, entry)
+}
+
+func (b *Business) UnbanUser(actor *domain.Session, userID int) error {
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(actor, domain.AuditUnban, domain.TargetUser, userID, user, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.SetUserBan(userID, false, "", entry)
+}
+
+// ForcePasswordReset replaces the user's password with a random one, ends
+// their sessions and mails them a reset link.
+func (b *Business) ForcePasswordReset(actor *domain.Session, userID int) error {
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	random, _, err := newToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(actor, domain.AuditResetPassword, domain.TargetUser, userID, user, "")
+	if err != nil {
+		return err
+	}
+	err = b.repo.UpdatePassword(userID, string(hashedPassword), &entry)
+	if err != nil {
+		return err
+	}
+	err = b.repo.InvalidateSessions(userID, nil)
+	if err != nil {
+		return err
+	}
+	return b.sendPasswordReset(user)
+}
+
+// SignOutUser ends every session of the user.
+func (b *Business) SignOutUser(actor *domain.Session, userID int) error {
+	user, err := b.managedUser(actor, userID)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(actor, domain.AuditSignOut, domain.TargetUser, userID, user, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.InvalidateSessions(userID, &entry)
+}
@@ -0,0 +1,49 @@
+package business
+
+import (
+	"encoding/json"
+	"time"
+
+	"forum/forum/domain"
+)
+
+const maxAuditEntries = 10000
+
+// auditEntry makes an audit log entry, with before stored as a JSON snapshot
+// of the target; pass nil when there is nothing to keep. A nil actor records
+// an action taken outside the web interface. The entry is handed to the repo
+// along with the change it records, and written in the same transaction.
+func auditEntry(actor *domain.Session, action domain.AuditAction, target domain.AuditTarget, targetID int, before any, reason string) (domain.AuditEntry, error) {
+	entry := domain.AuditEntry{
+		Action:       action,
+		TargetType:   target,
+		TargetId:     targetID,
+		Reason:       reason,
+		CreationDate: time.Now(),
+	}
+	if actor != nil {
+		entry.ActorId = actor.UserId
+		entry.ActorName = actor.Username
+	}
+	if before != nil {
+		snapshot, err := json.Marshal(before)
+		if err != nil {
+			return domain.AuditEntry{}, err
+		}
+		entry.Before = string(snapshot)
+	}
+	return entry, nil
+}
+
+// GetAuditLog returns the audit log entries matching filter, newest first.
+// At most maxAuditEntries are returned.
+func (b *Business) GetAuditLog(actor *domain.Session, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	err := b.Authorize(actor, domain.ActionManageUsers, 0)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 || filter.Limit > maxAuditEntries {
+		filter.Limit = maxAuditEntries
+	}
+	return b.repo.GetAuditLog(filter)
+}
@@ -0,0 +1,60 @@
+package business
+
+import "forum/forum/domain"
+
+// Authorize reports whether actor may perform action on something owned by
+// ownerID; pass 0 when the action does not concern existing content.
+//
+// Anyone signed in may create content, react and report, subject to
+// CheckPermission, unless they are suspended. Posts and comments may be
+// edited by their owner or an admin, and deleted or seen while hidden by
+// their owner, a moderator or an admin. Working the moderation queue and
+// managing tags takes a moderator, and managing users and categories an
+// admin.
+// Refusals are *domain.ForbiddenError, which matches domain.ErrForbidden.
+func (b *Business) Authorize(actor *domain.Session, action domain.Action, ownerID int) error {
+	if actor == nil || actor.UserId == 0 {
+		return &domain.ForbiddenError{Action: action}
+	}
+	owner := ownerID != 0 && actor.UserId == ownerID
+
+	switch action {
+	case domain.ActionPost, domain.ActionComment, domain.ActionReact, domain.ActionReport:
+		if actor.Suspended() {
+			return &domain.SuspendedError{Until: actor.SuspendedUntil}
+		}
+		return b.CheckPermission(actor, action)
+	case domain.ActionEditPost, domain.ActionEditComment:
+		if actor.Role.AtLeast(domain.RoleAdmin) {
+			return nil
+		}
+		if owner && actor.Suspended() {
+			return &domain.SuspendedError{Until: actor.SuspendedUntil}
+		}
+		if owner {
+			return b.CheckPermission(actor, baseAction(action))
+		}
+	case domain.ActionDeletePost, domain.ActionDeleteComment, domain.ActionViewHidden:
+		if owner || actor.Role.AtLeast(domain.RoleModerator) {
+			return nil
+		}
+	case domain.ActionModerate, domain.ActionManageTags:
+		if actor.Role.AtLeast(domain.RoleModerator) {
+			return nil
+		}
+	case domain.ActionManageUsers, domain.ActionManageCategories:
+		if actor.Role.AtLeast(domain.RoleAdmin) {
+			return nil
+		}
+	}
+	return &domain.ForbiddenError{Action: action}
+}
+
+// baseAction is the action that created the content an edit applies to, so
+// that editing is held to the same verification rules as writing.
+func baseAction(action domain.Action) domain.Action {
+	if action == domain.ActionEditComment {
+		return domain.ActionComment
+	}
+	return domain.ActionPost
+}
@@ -3,6 +3,7 @@ package business
 import (
 	"errors"
 	"fmt"
+	"log"
 	"regexp"
 	"time"
 
@@ -15,49 +16,119 @@ import (
 
 // Start of code
 type Business struct {
-	repo forum.Repo
+	repo   forum.Repo
+	mailer forum.Mailer
+	config Config
+	// newPosts wakes the saved search matcher when a post is written.
+	newPosts chan struct{}
 }
 
-func NewBusiness(repo forum.Repo) (*Business, error) {
+func NewBusiness(repo forum.Repo, mailer forum.Mailer, config Config) (*Business, error) {
 	return &Business{
-		repo: repo,
+		repo:     repo,
+		mailer:   mailer,
+		config:   config,
+		newPosts: make(chan struct{}, 1),
 	}, nil
 }
 
-func (b *Business) Login(username, password string) (uuid.UUID, error) {
-	user, err := b.repo.GetUser(username)
+// Login checks the user's password. Users with two-factor authentication get
+// a challenge token instead of a session, to be completed with
+// CompleteTwoFactorLogin.
+//
+// Failed attempts slow down further ones for the same username and IP address;
+// while blocked, Login fails with a *domain.ThrottleError.
+func (b *Business) Login(username, password string, client domain.Client) (uuid.UUID, string, error) {
+	err := b.checkThrottle(loginUserKey(username), loginIPKey(client))
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, "", err
+	}
+	user, err := b.repo.GetUser(username)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		if err != nil {
+			err = domain.ErrInvalidUser
+		}
+	}
+	if errors.Is(err, domain.ErrInvalidUser) {
+		failErr := b.loginFailed(username, client)
+		if failErr != nil {
+			return uuid.Nil, "", failErr
+		}
+		return uuid.Nil, "", err
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		err = domain.ErrInvalidUser
-		return uuid.Nil, err
+		return uuid.Nil, "", err
 	}
+	return b.startLogin(user, client)
+}
 
-	// Invalidate existing sessions for the user
-	err = b.repo.InvalidateSessions(user.UserId)
+// startLogin signs in a user whose first factor has been checked.
+func (b *Business) startLogin(user domain.User, client domain.Client) (uuid.UUID, string, error) {
+	tf, err := b.repo.GetTwoFactor(user.UserId)
+	if err == nil && tf.Enabled {
+		challenge, err := b.newLoginChallenge(user.UserId)
+		return uuid.Nil, challenge, err
+	}
+	if err != nil && !errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		return uuid.Nil, "", err
+	}
+
+	err = b.loginSucceeded(user.Username)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, "", err
 	}
+	sessionID, err := b.createSession(user, client)
+	return sessionID, "", err
+}
 
+// createSession is where every way of signing in ends, so banned users are
+// turned away here.
+func (b *Business) createSession(user domain.User, client domain.Client) (uuid.UUID, error) {
+	if user.Banned {
+		return uuid.Nil, &domain.BannedError{Reason: user.BanReason}
+	}
 	sessionID, err := uuid.NewV4()
 	if err != nil {
 		return uuid.Nil, err
 	}
 
+	now := time.Now()
 	session := domain.Session{
 		UserId:         user.UserId,
 		Username:       user.Username,
 		SessionId:      sessionID.String(),
-		CreationDate:   time.Now(),
-		ExpiritionDate: time.Now().Add(time.Hour * 24),
+		UserAgent:      client.UserAgent,
+		IP:             client.IP,
+		CreationDate:   now,
+		LastSeen:       now,
+		ExpiritionDate: b.sessionExpiration(now, now),
 	}
 	err = b.repo.SaveSession(session)
 	return sessionID, err
 }
 
-func (b *Business) Registration(username, password, email string) error {
+// SetUserRole changes the role of the user with the given name. It is used
+// from the command line, so the audit log records no actor.
+func (b *Business) SetUserRole(username string, role domain.Role) error {
+	user, err := b.repo.GetUser(username)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(nil, domain.AuditChangeRole, domain.TargetUser, user.UserId, user, "new role: "+string(role))
+	if err != nil {
+		return err
+	}
+	return b.repo.SetUserRole(user.UserId, role, entry)
+}
+
+// Registration creates an account. Only RegistrationsPerIP accounts may be
+// created from one IP address per RegistrationWindow.
+func (b *Business) Registration(username, password, email string, client domain.Client) error {
+	err := b.checkThrottle(registerIPKey(client))
+	if err != nil {
+		return err
+	}
 	if len(username) < 3 || len(username) > 15 {
 		return domain.ErrInvalidDataonRegistartion
 	}
@@ -97,43 +168,110 @@ func (b *Business) Registration(username, password, email string) error {
 	}
 
 	err = b.repo.SaveUser(newUser)
-	return err
-}
+	if err != nil {
+		return err
+	}
+	err = b.registered(client)
+	if err != nil {
+		return err
+	}
 
-func (b *Business) Session(sessionID string) (*domain.Session, error) {
-	session, err := b.repo.GetSession(sessionID)
+	user, err := b.repo.GetUser(username)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	err = b.sendVerification(user)
+	if err != nil {
+		// The account exists already; the user can ask for another link.
+		log.Printf("Error sending verification mail:%s", err)
+	}
+	return nil
+}
 
-	return &session, nil
+// Post saves a new post written by actor under the categories whose slugs
+// it names, if their rules let actor post there. Posts of shadow-banned
+// users are only listed for themselves.
+func (b *Business) Post(actor *domain.Session, posts domain.Posts) error {
+	err := b.Authorize(actor, domain.ActionPost, 0)
+	if err != nil {
+		return err
+	}
+	posts.Categories, err = b.resolveCategories(posts.Categories, nil)
+	if err != nil {
+		return err
+	}
+	posts.Tags, err = b.cleanTags(posts.Tags)
+	if err != nil {
+		return err
+	}
+	err = b.CheckCategoryRules(actor, posts.Categories, domain.ActionPost)
+	if err != nil {
+		return err
+	}
+	posts.UserId = actor.UserId
+	posts.Username = actor.Username
+	posts.Shadowed = actor.ShadowBanned
+	err = b.repo.SavePosts(posts)
+	if err != nil {
+		return err
+	}
+	b.wakeSearchAlerts()
+	return nil
 }
 
-func (b *Business) Post(posts domain.Posts) error {
-	err := b.repo.SavePosts(posts)
-	return err
+func viewerID(viewer *domain.Session) int {
+	if viewer == nil {
+		return 0
+	}
+	return viewer.UserId
 }
 
-func (b *Business) GetAllPosts() ([]domain.Posts, error) {
-	posts, err := b.repo.GetPosts()
+// GetAllPosts lists a page of the posts viewer may see in the order of
+// filter.Sort; viewer is nil for guests.
+func (b *Business) GetAllPosts(viewer *domain.Session, filter domain.PostFilter, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	posts, page, err := b.repo.GetPosts(filter, viewerID(viewer), req)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 
-	return posts, nil
+	return posts, page, nil
 }
 
-func (b *Business) GetMyPosts(userId int) ([]domain.Posts, error) {
-	posts, err := b.repo.GetUserPosts(userId)
+func (b *Business) GetMyPosts(userId int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	posts, page, err := b.repo.GetUserPosts(userId, req)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 
-	return posts, nil
+	return posts, page, nil
+}
+
+// DeletePost removes a post if actor owns it or moderates the forum.
+// Deleting someone else's post is recorded in the audit log.
+func (b *Business) DeletePost(actor *domain.Session, postId int) error {
+	return b.deletePost(actor, postId, false, "")
 }
 
-func (b *Business) DeletePost(postId int) error {
-	err := b.repo.DeletePost(postId)
+// deletePost is DeletePost, also recording the deletion of actor's own post
+// when audit is set, with reason.
+func (b *Business) deletePost(actor *domain.Session, postId int, audit bool, reason string) error {
+	post, err := b.repo.GetPostByID(postId)
+	if err != nil {
+		return err
+	}
+	err = b.Authorize(actor, domain.ActionDeletePost, post.UserId)
+	if err != nil {
+		return err
+	}
+	var entry *domain.AuditEntry
+	if audit || actor.UserId != post.UserId {
+		e, err := auditEntry(actor, domain.AuditDeletePost, domain.TargetPost, postId, post, reason)
+		if err != nil {
+			return err
+		}
+		entry = &e
+	}
+	err = b.repo.DeletePost(postId, entry)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -152,14 +290,32 @@ func (b *Business) GetPostByID(postId int) (domain.Posts, error) {
 	return post, nil
 }
 
-func (b *Business) AddComment(comment domain.Comments, userID, postID int) error {
-	err := b.repo.AddComment(comment)
+// AddComment saves a comment written by actor on an existing post whose
+// categories allow it. Like posts, comments of shadow-banned users are only
+// listed for themselves.
+func (b *Business) AddComment(actor *domain.Session, comment domain.Comments) error {
+	err := b.Authorize(actor, domain.ActionComment, 0)
+	if err != nil {
+		return err
+	}
+	post, err := b.repo.GetPostByID(comment.PostId)
+	if err != nil {
+		return err
+	}
+	err = b.CheckCategoryRules(actor, post.Categories, domain.ActionComment)
+	if err != nil {
+		return err
+	}
+	comment.UserId = actor.UserId
+	comment.Username = actor.Username
+	comment.Shadowed = actor.ShadowBanned
+	err = b.repo.AddComment(comment)
 
 	return err
 }
 
-func (b *Business) GetComments(postId int) ([]domain.Comments, error) {
-	comments, err := b.repo.GetComments(postId)
+func (b *Business) GetComments(viewer *domain.Session, postId int) ([]domain.Comments, error) {
+	comments, err := b.repo.GetComments(postId, viewerID(viewer))
 	if err != nil {
 		fmt.Println(err)
 		return comments, err
@@ -168,8 +324,36 @@ func (b *Business) GetComments(postId int) ([]domain.Comments, error) {
 	return comments, nil
 }
 
-func (b *Business) DeleteComment(comment_id int) error {
-	err := b.repo.DeleteComment(comment_id)
+func (b *Business) GetCommentByID(commentID int) (domain.Comments, error) {
+	return b.repo.GetCommentByID(commentID)
+}
+
+// DeleteComment removes a comment if actor owns it or moderates the forum.
+// Deleting someone else's comment is recorded in the audit log.
+func (b *Business) DeleteComment(actor *domain.Session, comment_id int) error {
+	return b.deleteComment(actor, comment_id, false, "")
+}
+
+// deleteComment is DeleteComment, also recording the deletion of actor's own
+// comment when audit is set, with reason.
+func (b *Business) deleteComment(actor *domain.Session, comment_id int, audit bool, reason string) error {
+	comment, err := b.repo.GetCommentByID(comment_id)
+	if err != nil {
+		return err
+	}
+	err = b.Authorize(actor, domain.ActionDeleteComment, comment.UserId)
+	if err != nil {
+		return err
+	}
+	var entry *domain.AuditEntry
+	if audit || actor.UserId != comment.UserId {
+		e, err := auditEntry(actor, domain.AuditDeleteComment, domain.TargetComment, comment_id, comment, reason)
+		if err != nil {
+			return err
+		}
+		entry = &e
+	}
+	err = b.repo.DeleteComment(comment_id, entry)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -188,16 +372,28 @@ func (b *Business) GetUserById(userId int) ([]domain.User, error) {
 }
 
 // LikePost allows a user to like a post and creates a notification.
-func (b *Business) LikePost(postID, userID, ownerID int, activity string, username string) error {
+// Reactions of shadow-banned users are accepted but not counted.
+func (b *Business) LikePost(actor *domain.Session, postID int, activity string) error {
+	err := b.Authorize(actor, domain.ActionReact, 0)
+	if err != nil {
+		return err
+	}
+	post, err := b.repo.GetPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if actor.ShadowBanned {
+		return nil
+	}
 	notification := domain.Notification{
-		UserId:    userID,
+		UserId:    actor.UserId,
 		Type:      activity,
 		PostId:    postID,
-		OwnerId:   ownerID,
-		Username:  username,
+		OwnerId:   post.UserId,
+		Username:  actor.Username,
 		Timestamp: time.Now(),
 	}
-	err := b.repo.LikePost(postID, userID, notification)
+	err = b.repo.LikePost(postID, actor.UserId, notification)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -209,18 +405,29 @@ func (b *Business) LikePost(postID, userID, ownerID int, activity string, userna
 }
 
 // DislikePost allows a user to dislike a post and creates a notification.
-func (b *Business) DislikePost(postID, userID, ownerID int, activity string, username string) error {
+func (b *Business) DislikePost(actor *domain.Session, postID int, activity string) error {
+	err := b.Authorize(actor, domain.ActionReact, 0)
+	if err != nil {
+		return err
+	}
+	post, err := b.repo.GetPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if actor.ShadowBanned {
+		return nil
+	}
 	notification := domain.Notification{
-		UserId:   userID,
+		UserId:   actor.UserId,
 		Type:     activity,
 		PostId:   postID,
-		OwnerId:  ownerID,
-		Username: username,
+		OwnerId:  post.UserId,
+		Username: actor.Username,
 
 		Timestamp: time.Now(),
 	}
 	// Call your repo's DislikePost function as usual
-	err := b.repo.DislikePost(postID, userID, notification)
+	err = b.repo.DislikePost(postID, actor.UserId, notification)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -231,64 +438,49 @@ func (b *Business) DislikePost(postID, userID, ownerID int, activity string, use
 	return nil
 }
 
-func (b *Business) GetLikedPosts(userID int) ([]domain.Posts, error) {
-	likedPostIDs, err := b.repo.GetLikedPostIDs(userID)
-	if err != nil {
-		return nil, err
-	}
-
-	var likedPosts []domain.Posts
-	for _, postID := range likedPostIDs {
-		fmt.Println(postID)
-		post, err := b.repo.GetPostByID(postID)
-		if err != nil {
-			fmt.Println(err)
-			return nil, err
-		}
-		likedPosts = append(likedPosts, post)
-	}
+func (b *Business) GetLikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return b.repo.GetLikedPosts(userID, req)
+}
 
-	return likedPosts, nil
+func (b *Business) GetDislikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return b.repo.GetDislikedPosts(userID, req)
 }
 
-func (b *Business) GetDislikedPosts(userID int) ([]domain.Posts, error) {
-	dislikedPostIDs, err := b.repo.GetDislikedPostIDs(userID)
+// GetPostsByCategories lists a page of the posts viewer may see in any of
+// the categories of filter, or in all of them with filter.MatchAll, in the
+// order of filter.Sort.
+func (b *Business) GetPostsByCategories(viewer *domain.Session, filter domain.PostFilter, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	filter.Categories = uniqueSlugs(filter.Categories)
+	posts, page, err := b.repo.GetPostsByCategories(filter, viewerID(viewer), req)
 	if err != nil {
-		return nil, err
-	}
-
-	var dislikedPosts []domain.Posts
-	for _, postID := range dislikedPostIDs {
-		post, err := b.repo.GetPostByID(postID)
-		if err != nil {
-			continue
-		}
-		dislikedPosts = append(dislikedPosts, post)
+		return nil, domain.Page{}, err
 	}
 
-	return dislikedPosts, nil
+	return posts, page, nil
 }
 
-func (b *Business) GetPostsByCategories(categories []string) ([]domain.Posts, error) {
-	posts, err := b.repo.GetPostsByCategories(categories)
+func (b *Business) LikeComment(actor *domain.Session, commentID int, activity string) error {
+	err := b.Authorize(actor, domain.ActionReact, 0)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	comment, err := b.repo.GetCommentByID(commentID)
+	if err != nil {
+		return err
+	}
+	if actor.ShadowBanned {
+		return nil
 	}
-
-	return posts, nil
-}
-
-func (b *Business) LikeComment(commentID int, userID int, ownerID int, activity string, username string, postID int) error {
 	notification := domain.Notification_comments{
-		UserId:    userID,
+		UserId:    actor.UserId,
 		Type:      activity,
 		CommentId: commentID,
-		OwnerId:   ownerID,
-		Username:  username,
-		PostId:    postID,
+		OwnerId:   comment.UserId,
+		Username:  actor.Username,
+		PostId:    comment.PostId,
 		Timestamp: time.Now(),
 	}
-	err := b.repo.LikeComment(commentID, userID, notification)
+	err = b.repo.LikeComment(commentID, actor.UserId, notification)
 	if err != nil {
 		return err
 	}
@@ -296,17 +488,28 @@ func (b *Business) LikeComment(commentID int, userID int, ownerID int, activity
 	return nil
 }
 
-func (b *Business) DislikeComment(commentID int, userID int, ownerID int, activity string, username string, postID int) error {
+func (b *Business) DislikeComment(actor *domain.Session, commentID int, activity string) error {
+	err := b.Authorize(actor, domain.ActionReact, 0)
+	if err != nil {
+		return err
+	}
+	comment, err := b.repo.GetCommentByID(commentID)
+	if err != nil {
+		return err
+	}
+	if actor.ShadowBanned {
+		return nil
+	}
 	notification := domain.Notification_comments{
-		UserId:    userID,
+		UserId:    actor.UserId,
 		Type:      activity,
 		CommentId: commentID,
-		OwnerId:   ownerID,
-		Username:  username,
-		PostId:    postID,
+		OwnerId:   comment.UserId,
+		Username:  actor.Username,
+		PostId:    comment.PostId,
 		Timestamp: time.Now(),
 	}
-	err := b.repo.DislikeComment(commentID, userID, notification)
+	err = b.repo.DislikeComment(commentID, actor.UserId, notification)
 	if err != nil {
 		return err
 	}
@@ -314,58 +517,100 @@ func (b *Business) DislikeComment(commentID int, userID int, ownerID int, activi
 	return nil
 }
 
-func (b *Business) GetUserActivity(userID int) (domain.UserActivity, error) {
-	// Query the database to get the user's created posts
-	createdPosts, err := b.repo.GetCreatedPosts(userID)
-	if err != nil {
-		return domain.UserActivity{}, err
-	}
-
+// GetUserActivity returns a page of the comments the user left; the page
+// tells where the pages around it are.
+func (b *Business) GetUserActivity(userID int, req domain.PageRequest) (domain.UserActivity, domain.Page, error) {
 	// Query the database to get the comments left by the user
-	comments, err := b.repo.GetCommentsByUser(userID)
+	comments, page, err := b.repo.GetCommentsByUser(userID, req)
 	if err != nil {
-		return domain.UserActivity{}, err
+		return domain.UserActivity{}, domain.Page{}, err
 	}
 
 	// Create a UserActivity struct to hold the retrieved data
 	activity := domain.UserActivity{
-		CreatedPosts: createdPosts,
-		Comments:     comments,
+		Comments: comments,
 	}
 
-	return activity, nil
+	return activity, page, nil
 }
 
-// GetAllNotifications retrieves general notifications for an owner.
-func (b *Business) GetAllNotifications(ownerID int) ([]domain.Notification, error) {
-	notifications, err := b.repo.GetAllNotifications(ownerID)
+// GetAllNotifications retrieves a page of general notifications for an owner.
+func (b *Business) GetAllNotifications(ownerID int, req domain.PageRequest) ([]domain.Notification, domain.Page, error) {
+	notifications, page, err := b.repo.GetAllNotifications(ownerID, req)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
-	return notifications, nil
+	return notifications, page, nil
 }
 
-// GetAllNotificationsComment retrieves comment-specific notifications for an owner.
-func (b *Business) GetAllNotificationsComment(ownerID int) ([]domain.Notification_comments, error) {
-	notifications, err := b.repo.GetAllNotificationsComment(ownerID)
+// GetAllNotificationsComment retrieves a page of comment-specific
+// notifications for an owner.
+func (b *Business) GetAllNotificationsComment(ownerID int, req domain.PageRequest) ([]domain.Notification_comments, domain.Page, error) {
+	notifications, page, err := b.repo.GetAllNotificationsComment(ownerID, req)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
-	return notifications, nil
+	return notifications, page, nil
 }
 
-func (b *Business) EditPost(postId int, post domain.Posts) error {
-	err := b.repo.EditPost(postId, post)
+// EditPost replaces the content of a post if actor may edit it. An admin
+// editing someone else's post is recorded in the audit log.
+func (b *Business) EditPost(actor *domain.Session, postId int, post domain.Posts) error {
+	existing, err := b.repo.GetPostByID(postId)
 	if err != nil {
 		return err
 	}
-	return nil
+	err = b.Authorize(actor, domain.ActionEditPost, existing.UserId)
+	if err != nil {
+		return err
+	}
+	post.Categories, err = b.resolveCategories(post.Categories, existing.Categories)
+	if err != nil {
+		return err
+	}
+	post.Tags, err = b.cleanTags(post.Tags)
+	if err != nil {
+		return err
+	}
+	var added []domain.Category
+	for _, c := range post.Categories {
+		if !containsCategory(existing.Categories, c.Id) {
+			added = append(added, c)
+		}
+	}
+	err = b.CheckCategoryRules(actor, added, domain.ActionPost)
+	if err != nil {
+		return err
+	}
+	var entry *domain.AuditEntry
+	if actor.UserId != existing.UserId {
+		e, err := auditEntry(actor, domain.AuditEditPost, domain.TargetPost, postId, existing, "")
+		if err != nil {
+			return err
+		}
+		entry = &e
+	}
+	return b.repo.EditPost(postId, post, entry)
 }
 
-func (b *Business) EditComment(commentId int, comment domain.Comments) error {
-	err := b.repo.EditComment(commentId, comment)
+// EditComment replaces the text of a comment if actor may edit it. An admin
+// editing someone else's comment is recorded in the audit log.
+func (b *Business) EditComment(actor *domain.Session, commentId int, comment domain.Comments) error {
+	existing, err := b.repo.GetCommentByID(commentId)
 	if err != nil {
 		return err
 	}
-	return nil
+	err = b.Authorize(actor, domain.ActionEditComment, existing.UserId)
+	if err != nil {
+		return err
+	}
+	var entry *domain.AuditEntry
+	if actor.UserId != existing.UserId {
+		e, err := auditEntry(actor, domain.AuditEditComment, domain.TargetComment, commentId, existing, "")
+		if err != nil {
+			return err
+		}
+		entry = &e
+	}
+	return b.repo.EditComment(commentId, comment, entry)
 }
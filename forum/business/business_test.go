@@ -0,0 +1,58 @@
+package business
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"forum/forum/domain"
+	"forum/forum/mailer"
+	"forum/forum/repo"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestBusiness returns a Business on a new database in a temporary
+// directory, mailing nowhere.
+func newTestBusiness(t *testing.T, config Config) *Business {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	rep, err := repo.NewDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBusiness(rep, mailer.NewWriterMailer(io.Discard, "forum@localhost"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func addTestUser(t *testing.T, b *Business, username, email string, verified bool) domain.User {
+	t.Helper()
+	err := b.repo.SaveUser(domain.User{
+		Username:      username,
+		Email:         email,
+		Password:      "unusable",
+		EmailVerified: verified,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := b.repo.GetUser(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+var testClient = domain.Client{UserAgent: "test", IP: "192.0.2.1"}
@@ -0,0 +1,265 @@
+package business
+
+import (
+	"strings"
+
+	"forum/forum/domain"
+)
+
+// GetCategories returns every category in display order.
+func (b *Business) GetCategories() ([]domain.Category, error) {
+	return b.repo.GetCategories()
+}
+
+// resolveCategories looks up the categories chosen for a post by their slugs
+// and drops duplicates. A post needs at least one existing category, and may
+// only be filed under an archived category it was in already, as listed in
+// kept.
+func (b *Business) resolveCategories(chosen []domain.Category, kept []domain.Category) ([]domain.Category, error) {
+	if len(chosen) == 0 {
+		return nil, domain.ErrNoCategory
+	}
+	categories, err := b.repo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]domain.Category, len(categories))
+	for _, c := range categories {
+		bySlug[c.Slug] = c
+	}
+
+	var resolved []domain.Category
+	seen := make(map[string]bool)
+	for _, c := range chosen {
+		category, ok := bySlug[c.Slug]
+		if !ok || (category.Archived && !containsCategory(kept, category.Id)) {
+			return nil, domain.ErrInvalidCategory
+		}
+		if !seen[c.Slug] {
+			seen[c.Slug] = true
+			resolved = append(resolved, category)
+		}
+	}
+	return resolved, nil
+}
+
+func containsCategory(categories []domain.Category, id int) bool {
+	for _, c := range categories {
+		if c.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCategoryRules reports whether actor may start a post (ActionPost) or
+// comment (ActionComment) in each of the categories. Moderators are exempt
+// from category rules.
+func (b *Business) CheckCategoryRules(actor *domain.Session, categories []domain.Category, action domain.Action) error {
+	if actor != nil && actor.Role.AtLeast(domain.RoleModerator) {
+		return nil
+	}
+	for _, c := range categories {
+		switch {
+		case c.ReadOnly:
+			return &domain.CategoryRuleError{Category: c.Name, Rule: "only moderators write in this announcement category"}
+		case action == domain.ActionComment && !c.AllowComments:
+			return &domain.CategoryRuleError{Category: c.Name, Rule: "comments are turned off in this category"}
+		case action == domain.ActionPost && c.PostPolicy == domain.PostModerators:
+			return &domain.CategoryRuleError{Category: c.Name, Rule: "only moderators may start posts in this category"}
+		case action == domain.ActionPost && c.PostPolicy == domain.PostVerified && (actor == nil || !actor.EmailVerified):
+			return domain.ErrEmailNotVerified
+		}
+	}
+	return nil
+}
+
+// uniqueSlugs returns slugs without blanks and repeats, so that matching all
+// of them counts each category once.
+func uniqueSlugs(slugs []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, slug := range slugs {
+		if slug != "" && !seen[slug] {
+			seen[slug] = true
+			unique = append(unique, slug)
+		}
+	}
+	return unique
+}
+
+// CreateCategory adds a category at the end of the list. Without a slug, one
+// is made from the name.
+func (b *Business) CreateCategory(actor *domain.Session, category domain.Category) error {
+	err := b.Authorize(actor, domain.ActionManageCategories, 0)
+	if err != nil {
+		return err
+	}
+	category, err = cleanCategory(category)
+	if err != nil {
+		return err
+	}
+	// The category has no id to record before it is saved, so its entry is
+	// written along with it.
+	entry, err := auditEntry(actor, domain.AuditCreateCategory, domain.TargetCategory, 0, nil, category.Name)
+	if err != nil {
+		return err
+	}
+	_, err = b.repo.SaveCategory(category, entry)
+	return err
+}
+
+// UpdateCategory renames a category and changes its rules. Its position and
+// whether it is archived stay as they are.
+func (b *Business) UpdateCategory(actor *domain.Session, category domain.Category) error {
+	existing, err := b.managedCategory(actor, category.Id)
+	if err != nil {
+		return err
+	}
+	category, err = cleanCategory(category)
+	if err != nil {
+		return err
+	}
+	category.Position = existing.Position
+	category.Archived = existing.Archived
+	entry, err := auditEntry(actor, domain.AuditEditCategory, domain.TargetCategory, category.Id, existing, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.UpdateCategory(category, entry)
+}
+
+// ArchiveCategory stops or, with archived false, resumes new posts in a
+// category. Its posts stay where they are.
+func (b *Business) ArchiveCategory(actor *domain.Session, id int, archived bool) error {
+	category, err := b.managedCategory(actor, id)
+	if err != nil {
+		return err
+	}
+	before := category
+	category.Archived = archived
+	action := domain.AuditArchiveCategory
+	if !archived {
+		action = domain.AuditUnarchiveCategory
+	}
+	entry, err := auditEntry(actor, action, domain.TargetCategory, id, before, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.UpdateCategory(category, entry)
+}
+
+// MoveCategory moves a category up (offset -1) or down (offset 1) the list.
+func (b *Business) MoveCategory(actor *domain.Session, id int, offset int) error {
+	category, err := b.managedCategory(actor, id)
+	if err != nil {
+		return err
+	}
+	categories, err := b.repo.GetCategories()
+	if err != nil {
+		return err
+	}
+	ids := make([]int, len(categories))
+	from := 0
+	for i, c := range categories {
+		ids[i] = c.Id
+		if c.Id == id {
+			from = i
+		}
+	}
+	to := from + offset
+	if to < 0 || to >= len(ids) {
+		return nil
+	}
+	ids[from], ids[to] = ids[to], ids[from]
+	entry, err := auditEntry(actor, domain.AuditMoveCategory, domain.TargetCategory, id, category, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.SetCategoryOrder(ids, entry)
+}
+
+// MergeCategory files every post of the category fromID under intoID and
+// deletes fromID.
+func (b *Business) MergeCategory(actor *domain.Session, fromID, intoID int) error {
+	from, err := b.managedCategory(actor, fromID)
+	if err != nil {
+		return err
+	}
+	if fromID == intoID {
+		return domain.ErrMergeSameCategory
+	}
+	into, err := b.repo.GetCategory(intoID)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(actor, domain.AuditMergeCategory, domain.TargetCategory, fromID, from, "merged into "+into.Name)
+	if err != nil {
+		return err
+	}
+	return b.repo.MergeCategories(fromID, intoID, entry)
+}
+
+// managedCategory checks that actor may manage categories and returns the
+// category with id.
+func (b *Business) managedCategory(actor *domain.Session, id int) (domain.Category, error) {
+	err := b.Authorize(actor, domain.ActionManageCategories, 0)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	return b.repo.GetCategory(id)
+}
+
+// cleanCategory trims the fields of a category an admin entered, derives a
+// missing slug from the name and checks the result.
+func cleanCategory(c domain.Category) (domain.Category, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	c.Slug = strings.ToLower(strings.TrimSpace(c.Slug))
+	if c.Slug == "" {
+		c.Slug = slugify(c.Name)
+	}
+	if c.Name == "" || !validSlug(c.Slug) {
+		return c, domain.ErrInvalidCategoryName
+	}
+	if c.PostPolicy == "" {
+		c.PostPolicy = domain.PostEveryone
+	}
+	for _, p := range domain.PostPolicies {
+		if p == c.PostPolicy {
+			return c, nil
+		}
+	}
+	return c, domain.ErrInvalidPostPolicy
+}
+
+// slugify turns a name into a slug: lower case letters and digits, with a
+// single - wherever anything else was.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+func validSlug(slug string) bool {
+	if slug == "" || len(slug) > 50 {
+		return false
+	}
+	for _, r := range slug {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-') {
+			return false
+		}
+	}
+	return true
+}
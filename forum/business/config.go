@@ -0,0 +1,81 @@
+package business
+
+import (
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/oauth"
+)
+
+// Config holds the tunable settings of the business layer.
+type Config struct {
+	// BaseURL is the public address of the forum, used in links sent by mail.
+	// Its host is also the WebAuthn relying party ID, so passkeys only work
+	// when the forum is reached through this address.
+	BaseURL string
+	// SessionIdleTimeout is how long a session stays valid without activity.
+	// Every authenticated request pushes the expiry forward by this amount.
+	SessionIdleTimeout time.Duration
+	// SessionMaxLifetime caps the total age of a session regardless of activity.
+	SessionMaxLifetime time.Duration
+	// SessionReapInterval is how often expired sessions are purged.
+	SessionReapInterval time.Duration
+	// SearchAlertInterval is how often saved searches are matched against
+	// new posts when no post wakes the matcher earlier.
+	SearchAlertInterval time.Duration
+	// SearchDigestInterval is how often the posts found by saved searches
+	// are mailed to the users who asked for a digest.
+	SearchDigestInterval time.Duration
+	// PasswordResetTTL is how long a password reset link stays valid.
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is how long an email verification link stays valid.
+	EmailVerificationTTL time.Duration
+	// UnverifiedActions lists what users may do before verifying their email.
+	// Reading is always allowed.
+	UnverifiedActions []domain.Action
+	// OAuthProviders are the external identity providers users may sign in
+	// with. Their callback URL is BaseURL + "/auth/<name>/callback".
+	OAuthProviders []oauth.Provider
+
+	// LoginFreeAttempts is how many logins to one username may fail before
+	// each further failure blocks that username for twice as long as the last,
+	// starting at LoginBackoffBase and capped at LoginBackoffMax.
+	LoginFreeAttempts int
+	// IPFreeAttempts is the same allowance for failures from one IP address,
+	// across all usernames.
+	IPFreeAttempts   int
+	LoginBackoffBase time.Duration
+	LoginBackoffMax  time.Duration
+	// LockoutThreshold failures lock the username for LockoutDuration and
+	// notify its owner by mail.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// FailureWindow is how long failures are remembered after the last one.
+	FailureWindow time.Duration
+	// RegistrationsPerIP accounts may be created from one IP address per
+	// RegistrationWindow.
+	RegistrationsPerIP int
+	RegistrationWindow time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:              "http://localhost:8080",
+		SessionIdleTimeout:   24 * time.Hour,
+		SessionMaxLifetime:   7 * 24 * time.Hour,
+		SessionReapInterval:  10 * time.Minute,
+		SearchAlertInterval:  time.Minute,
+		SearchDigestInterval: 24 * time.Hour,
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: 48 * time.Hour,
+		LoginFreeAttempts:    3,
+		IPFreeAttempts:       10,
+		LoginBackoffBase:     time.Second,
+		LoginBackoffMax:      15 * time.Minute,
+		LockoutThreshold:     10,
+		LockoutDuration:      time.Hour,
+		FailureWindow:        24 * time.Hour,
+		RegistrationsPerIP:   5,
+		RegistrationWindow:   time.Hour,
+	}
+}
@@ -0,0 +1,9 @@
+package business
+
+import "forum/forum/markdown"
+
+// PreviewMarkdown renders text the way a post or comment with that text
+// would be shown.
+func (b *Business) PreviewMarkdown(text string) string {
+	return markdown.Render(text)
+}
@@ -0,0 +1,245 @@
+package business
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/oauth"
+
+	"github.com/gofrs/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func (b *Business) OAuthProviders() []domain.IdentityProvider {
+	var providers []domain.IdentityProvider
+	for _, p := range b.config.OAuthProviders {
+		providers = append(providers, domain.IdentityProvider{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			Icon:        p.Icon(),
+		})
+	}
+	return providers
+}
+
+func (b *Business) oauthProvider(name string) (oauth.Provider, error) {
+	for _, p := range b.config.OAuthProviders {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, domain.ErrUnknownProvider
+}
+
+func (b *Business) oauthRedirectURI(provider string) string {
+	return strings.TrimRight(b.config.BaseURL, "/") + "/auth/" + provider + "/callback"
+}
+
+// BeginOAuth starts a sign-in with an external provider and returns the URL
+// to send the user to, together with the state the callback must carry.
+// With a non-zero linkUserID the provider account is connected to that user
+// instead of signing in.
+func (b *Business) BeginOAuth(providerName string, linkUserID int) (string, string, error) {
+	provider, err := b.oauthProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+	state, stateHash, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oauth.NewVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, challenge, b.oauthRedirectURI(providerName))
+	if err != nil {
+		return "", "", err
+	}
+	err = b.repo.SaveOAuthState(domain.OAuthState{
+		StateHash:      stateHash,
+		Provider:       providerName,
+		CodeVerifier:   verifier,
+		UserId:         linkUserID,
+		ExpirationDate: time.Now().Add(oauthStateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// FinishOAuth handles the provider's callback. Like Login it returns either a
+// session or a two-factor challenge. When the flow was started to connect an
+// account, the identity is linked to session's user and neither is returned.
+//
+// A provider account signs in the user it is linked to. Otherwise it is
+// linked to the user with the same email address if both sides have verified
+// it, or a new account is created when no user has that address.
+func (b *Business) FinishOAuth(providerName, state, code string, client domain.Client, session *domain.Session) (uuid.UUID, string, error) {
+	provider, err := b.oauthProvider(providerName)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	stored, err := b.repo.ConsumeOAuthState(hashToken(state), providerName, time.Now())
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	identity, err := provider.Identity(code, stored.CodeVerifier, b.oauthRedirectURI(providerName))
+	if err != nil {
+		log.Printf("Error with %s sign-in:%s", providerName, err)
+		return uuid.Nil, "", domain.ErrOAuthFailed
+	}
+
+	if stored.UserId != 0 {
+		if session == nil || session.UserId != stored.UserId {
+			return uuid.Nil, "", domain.ErrOAuthFailed
+		}
+		return uuid.Nil, "", b.linkIdentity(stored.UserId, providerName, identity)
+	}
+
+	linked, err := b.repo.GetUserIdentity(providerName, identity.Subject)
+	if err == nil {
+		users, err := b.repo.GetUserById(linked.UserId)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		if len(users) == 0 {
+			return uuid.Nil, "", domain.ErrInvalidUser
+		}
+		return b.startLogin(users[0], client)
+	} else if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return uuid.Nil, "", err
+	}
+
+	if identity.Email == "" {
+		return uuid.Nil, "", domain.ErrOAuthNoEmail
+	}
+	user, err := b.repo.GetUserByEmail(identity.Email)
+	if err == nil {
+		// Linking on an unverified address on either side would let someone
+		// who registered a victim's email take over the account.
+		if !identity.EmailVerified || !user.EmailVerified {
+			return uuid.Nil, "", domain.ErrOAuthEmailTaken
+		}
+	} else if errors.Is(err, domain.ErrInvalidUser) {
+		user, err = b.createOAuthUser(identity)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+	} else {
+		return uuid.Nil, "", err
+	}
+
+	err = b.linkIdentity(user.UserId, providerName, identity)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return b.startLogin(user, client)
+}
+
+func (b *Business) linkIdentity(userID int, providerName string, identity oauth.Identity) error {
+	linked, err := b.repo.GetUserIdentity(providerName, identity.Subject)
+	if err == nil {
+		if linked.UserId != userID {
+			return domain.ErrIdentityTaken
+		}
+		return nil
+	} else if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return err
+	}
+	return b.repo.SaveUserIdentity(domain.UserIdentity{
+		UserId:       userID,
+		Provider:     providerName,
+		Subject:      identity.Subject,
+		Email:        identity.Email,
+		CreationDate: time.Now(),
+	})
+}
+
+// createOAuthUser registers a user signing in with a provider for the first
+// time. The account gets an unusable random password; the user can set one
+// through the password reset flow.
+func (b *Business) createOAuthUser(identity oauth.Identity) (domain.User, error) {
+	username, err := b.freeUsername(identity)
+	if err != nil {
+		return domain.User{}, err
+	}
+	password, _, err := newToken()
+	if err != nil {
+		return domain.User{}, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	err = b.repo.SaveUser(domain.User{
+		Username:      username,
+		Email:         identity.Email,
+		Password:      string(hashedPassword),
+		EmailVerified: identity.EmailVerified,
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	user, err := b.repo.GetUser(username)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !user.EmailVerified {
+		err = b.sendVerification(user)
+		if err != nil {
+			log.Printf("Error sending verification mail:%s", err)
+		}
+	}
+	return user, nil
+}
+
+// freeUsername derives an unused username from the provider's suggestion or
+// the email address, within the length limits of Registration.
+func (b *Business) freeUsername(identity oauth.Identity) (string, error) {
+	base := usernameUnsafe.ReplaceAllString(identity.Username, "")
+	if base == "" {
+		base = usernameUnsafe.ReplaceAllString(strings.SplitN(identity.Email, "@", 2)[0], "")
+	}
+	if len(base) > 12 {
+		base = base[:12]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	for i := 1; i < 1000; i++ {
+		candidate := base
+		if i > 1 {
+			candidate += strconv.Itoa(i)
+		}
+		_, err := b.repo.GetUser(candidate)
+		if errors.Is(err, domain.ErrInvalidUser) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", domain.ErrUserAlreadyExist
+}
+
+func (b *Business) GetUserIdentities(userID int) ([]domain.UserIdentity, error) {
+	return b.repo.GetUserIdentities(userID)
+}
+
+func (b *Business) UnlinkIdentity(userID, id int) error {
+	return b.repo.DeleteUserIdentity(userID, id)
+}
@@ -0,0 +1,278 @@
+package business
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/oauth"
+	"forum/forum/oauth/mockidp"
+
+	"github.com/gofrs/uuid"
+)
+
+// newOAuthTest returns a Business signing in with the mock IdP, served
+// locally as provider "mock".
+func newOAuthTest(t *testing.T) (*Business, oauth.Provider) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	idp := mockidp.New("http://"+srv.Listener.Addr().String(), "forum", "secret")
+	srv.Config.Handler = idp
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	provider, err := oauth.NewProvider(oauth.ProviderConfig{
+		Name:         "mock",
+		Type:         "oidc",
+		Issuer:       idp.Issuer,
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.OAuthProviders = []oauth.Provider{provider}
+	return newTestBusiness(t, config), provider
+}
+
+// authorize signs in at the mock IdP as email and returns the code and state
+// it sends back to the forum.
+func authorize(t *testing.T, authURL, email string, verified bool) (string, string) {
+	t.Helper()
+	form := url.Values{"email": {email}}
+	if verified {
+		form.Set("email_verified", "1")
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.PostForm(authURL, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("mock IdP answered %d", resp.StatusCode)
+	}
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+// signIn goes through the whole flow as email and returns what FinishOAuth
+// does.
+func signIn(t *testing.T, b *Business, email string, verified bool, linkUserID int, session *domain.Session) (uuid.UUID, error) {
+	t.Helper()
+	authURL, state, err := b.BeginOAuth("mock", linkUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, returned := authorize(t, authURL, email, verified)
+	if returned != state {
+		t.Fatalf("state came back as %q, want %q", returned, state)
+	}
+	sessionID, _, err := b.FinishOAuth("mock", state, code, testClient, session)
+	return sessionID, err
+}
+
+func sessionUser(t *testing.T, b *Business, sessionID uuid.UUID) int {
+	t.Helper()
+	session, err := b.Session(sessionID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session.UserId
+}
+
+func TestOAuthCreatesUser(t *testing.T) {
+	b, _ := newOAuthTest(t)
+
+	sessionID, err := signIn(t, b, "new@example.com", true, 0, nil)
+	if err != nil {
+		t.Fatalf("first sign-in: %v", err)
+	}
+	user, err := b.repo.GetUserByEmail("new@example.com")
+	if err != nil {
+		t.Fatalf("no user created: %v", err)
+	}
+	if !user.EmailVerified {
+		t.Error("the address verified by the provider is not verified")
+	}
+	if got := sessionUser(t, b, sessionID); got != user.UserId {
+		t.Fatalf("signed in as user %d, want %d", got, user.UserId)
+	}
+
+	sessionID, err = signIn(t, b, "new@example.com", true, 0, nil)
+	if err != nil {
+		t.Fatalf("second sign-in: %v", err)
+	}
+	if got := sessionUser(t, b, sessionID); got != user.UserId {
+		t.Fatalf("second sign-in as user %d, want %d", got, user.UserId)
+	}
+}
+
+func TestOAuthLinksVerifiedEmail(t *testing.T) {
+	b, _ := newOAuthTest(t)
+	verified := addTestUser(t, b, "verified", "verified@example.com", true)
+	addTestUser(t, b, "unverified", "unverified@example.com", false)
+
+	_, err := signIn(t, b, "unverified@example.com", true, 0, nil)
+	if !errors.Is(err, domain.ErrOAuthEmailTaken) {
+		t.Fatalf("address unverified on the forum: got %v, want ErrOAuthEmailTaken", err)
+	}
+	_, err = signIn(t, b, "verified@example.com", false, 0, nil)
+	if !errors.Is(err, domain.ErrOAuthEmailTaken) {
+		t.Fatalf("address unverified by the provider: got %v, want ErrOAuthEmailTaken", err)
+	}
+	identities, err := b.GetUserIdentities(verified.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 0 {
+		t.Fatalf("refused sign-in linked %+v", identities)
+	}
+
+	sessionID, err := signIn(t, b, "verified@example.com", true, 0, nil)
+	if err != nil {
+		t.Fatalf("address verified on both sides: %v", err)
+	}
+	if got := sessionUser(t, b, sessionID); got != verified.UserId {
+		t.Fatalf("signed in as user %d, want %d", got, verified.UserId)
+	}
+	identities, err = b.GetUserIdentities(verified.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 1 || identities[0].Provider != "mock" {
+		t.Fatalf("identities after linking: %+v", identities)
+	}
+}
+
+func TestOAuthRejectsState(t *testing.T) {
+	b, provider := newOAuthTest(t)
+
+	authURL, state, err := b.BeginOAuth("mock", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, authURL, "state@example.com", true)
+	_, _, err = b.FinishOAuth("mock", "not-the-state", code, testClient, nil)
+	if !errors.Is(err, domain.ErrOAuthFailed) {
+		t.Fatalf("wrong state: got %v, want ErrOAuthFailed", err)
+	}
+	_, _, err = b.FinishOAuth("mock", state, code, testClient, nil)
+	if err != nil {
+		t.Fatalf("right state: %v", err)
+	}
+	_, _, err = b.FinishOAuth("mock", state, code, testClient, nil)
+	if !errors.Is(err, domain.ErrOAuthFailed) {
+		t.Fatalf("replayed state: got %v, want ErrOAuthFailed", err)
+	}
+
+	// A state past its expiration, for a code the IdP would redeem.
+	state, stateHash, err := newToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, challenge, err := oauth.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err = provider.AuthCodeURL(state, challenge, b.oauthRedirectURI("mock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.repo.SaveOAuthState(domain.OAuthState{
+		StateHash:      stateHash,
+		Provider:       "mock",
+		CodeVerifier:   verifier,
+		ExpirationDate: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ = authorize(t, authURL, "state@example.com", true)
+	_, _, err = b.FinishOAuth("mock", state, code, testClient, nil)
+	if !errors.Is(err, domain.ErrOAuthFailed) {
+		t.Fatalf("expired state: got %v, want ErrOAuthFailed", err)
+	}
+}
+
+func TestOAuthRejectsWrongVerifier(t *testing.T) {
+	b, _ := newOAuthTest(t)
+
+	authURL, _, err := b.BeginOAuth("mock", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := b.BeginOAuth("mock", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The code is bound to the challenge of the first flow, and the second
+	// flow's state redeems it with its own verifier.
+	code, _ := authorize(t, authURL, "pkce@example.com", true)
+	_, _, err = b.FinishOAuth("mock", other, code, testClient, nil)
+	if !errors.Is(err, domain.ErrOAuthFailed) {
+		t.Fatalf("wrong verifier: got %v, want ErrOAuthFailed", err)
+	}
+	_, err = b.repo.GetUserByEmail("pkce@example.com")
+	if !errors.Is(err, domain.ErrInvalidUser) {
+		t.Fatalf("failed sign-in created a user: %v", err)
+	}
+}
+
+func TestOAuthLinkNeedsSession(t *testing.T) {
+	b, _ := newOAuthTest(t)
+	owner := addTestUser(t, b, "owner", "owner@example.com", true)
+	other := addTestUser(t, b, "other", "other@example.com", true)
+	session := func(user domain.User) *domain.Session {
+		sessionID, err := b.createSession(user, testClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := b.Session(sessionID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	_, err := signIn(t, b, "elsewhere@example.com", true, owner.UserId, nil)
+	if !errors.Is(err, domain.ErrOAuthFailed) {
+		t.Fatalf("link without a session: got %v, want ErrOAuthFailed", err)
+	}
+	_, err = signIn(t, b, "elsewhere@example.com", true, owner.UserId, session(other))
+	if !errors.Is(err, domain.ErrOAuthFailed) {
+		t.Fatalf("link with another user's session: got %v, want ErrOAuthFailed", err)
+	}
+	for _, user := range []domain.User{owner, other} {
+		identities, err := b.GetUserIdentities(user.UserId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(identities) != 0 {
+			t.Fatalf("refused link connected %+v", identities)
+		}
+	}
+
+	sessionID, err := signIn(t, b, "elsewhere@example.com", true, owner.UserId, session(owner))
+	if err != nil {
+		t.Fatalf("link with the owner's session: %v", err)
+	}
+	if sessionID != uuid.Nil {
+		t.Error("linking started a session")
+	}
+	identities, err := b.GetUserIdentities(owner.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 1 || identities[0].Email != "elsewhere@example.com" {
+		t.Fatalf("identities after linking: %+v", identities)
+	}
+}
@@ -0,0 +1,206 @@
+package business
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/webauthn"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	passkeyChallengeTTL   = 5 * time.Minute
+	passkeyNameMaxLength  = 50
+	passkeyDefaultName    = "Passkey"
+	challengeRegistration = "register"
+	challengeLogin        = "login"
+)
+
+// relyingParty derives the WebAuthn relying party from BaseURL. Browsers only
+// allow passkeys on the host the forum is actually served from.
+func (b *Business) relyingParty() webauthn.RelyingParty {
+	u, err := url.Parse(b.config.BaseURL)
+	if err != nil || u.Host == "" {
+		log.Printf("Error with base url for passkeys:%s", b.config.BaseURL)
+		return webauthn.RelyingParty{}
+	}
+	return webauthn.RelyingParty{
+		ID:     u.Hostname(),
+		Name:   totpIssuer,
+		Origin: u.Scheme + "://" + u.Host,
+	}
+}
+
+func (b *Business) newWebAuthnChallenge(userID int, typ string) ([]byte, error) {
+	challenge := make([]byte, 32)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, err
+	}
+	err = b.repo.SaveWebAuthnChallenge(domain.WebAuthnChallenge{
+		UserId:         userID,
+		ChallengeHash:  hashChallenge(challenge),
+		Type:           typ,
+		ExpirationDate: time.Now().Add(passkeyChallengeTTL),
+	})
+	return challenge, err
+}
+
+// consumeWebAuthnChallenge finds the ceremony a response belongs to from the
+// challenge the browser signed.
+func (b *Business) consumeWebAuthnChallenge(clientDataJSON []byte, typ string) ([]byte, domain.WebAuthnChallenge, error) {
+	challenge, err := webauthn.Challenge(clientDataJSON)
+	if err != nil {
+		return nil, domain.WebAuthnChallenge{}, domain.ErrInvalidPasskey
+	}
+	stored, err := b.repo.ConsumeWebAuthnChallenge(hashChallenge(challenge), typ, time.Now())
+	return challenge, stored, err
+}
+
+func hashChallenge(challenge []byte) string {
+	sum := sha256.Sum256(challenge)
+	return hex.EncodeToString(sum[:])
+}
+
+// BeginPasskeyRegistration returns the options for navigator.credentials.create.
+// Passkeys the user already has are excluded so an authenticator is not
+// registered twice.
+func (b *Business) BeginPasskeyRegistration(userID int, username string) (webauthn.CreationOptions, error) {
+	passkeys, err := b.repo.GetPasskeys(userID)
+	if err != nil {
+		return webauthn.CreationOptions{}, err
+	}
+	var exclude [][]byte
+	for _, passkey := range passkeys {
+		exclude = append(exclude, passkey.CredentialId)
+	}
+
+	challenge, err := b.newWebAuthnChallenge(userID, challengeRegistration)
+	if err != nil {
+		return webauthn.CreationOptions{}, err
+	}
+	user := webauthn.UserEntity{
+		ID:          []byte(strconv.Itoa(userID)),
+		Name:        username,
+		DisplayName: username,
+	}
+	return b.relyingParty().NewCreationOptions(challenge, user, exclude), nil
+}
+
+func (b *Business) FinishPasskeyRegistration(userID int, name string, resp webauthn.AttestationResponse) error {
+	challenge, stored, err := b.consumeWebAuthnChallenge(resp.Response.ClientDataJSON, challengeRegistration)
+	if err != nil {
+		return err
+	}
+	if stored.UserId != userID {
+		return domain.ErrInvalidPasskey
+	}
+
+	credential, err := b.relyingParty().VerifyRegistration(challenge, resp)
+	if err != nil {
+		log.Printf("Error with passkey registration:%s", err)
+		return domain.ErrInvalidPasskey
+	}
+	_, err = b.repo.GetPasskeyByCredentialID(credential.ID)
+	if err == nil {
+		return domain.ErrInvalidPasskey
+	} else if !errors.Is(err, domain.ErrPasskeyNotFound) {
+		return err
+	}
+
+	return b.repo.SavePasskey(domain.Passkey{
+		UserId:       userID,
+		CredentialId: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		Name:         passkeyName(name),
+		CreationDate: time.Now(),
+	})
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get. No
+// credentials are listed: passkeys are discoverable, so the browser offers
+// the ones it holds for this site.
+func (b *Business) BeginPasskeyLogin() (webauthn.RequestOptions, error) {
+	challenge, err := b.newWebAuthnChallenge(0, challengeLogin)
+	if err != nil {
+		return webauthn.RequestOptions{}, err
+	}
+	return b.relyingParty().NewRequestOptions(challenge), nil
+}
+
+// FinishPasskeyLogin verifies a passkey assertion and starts a session. The
+// authenticator has verified the user itself, so no second factor is asked.
+func (b *Business) FinishPasskeyLogin(resp webauthn.AssertionResponse, client domain.Client) (uuid.UUID, error) {
+	challenge, _, err := b.consumeWebAuthnChallenge(resp.Response.ClientDataJSON, challengeLogin)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	passkey, err := b.repo.GetPasskeyByCredentialID(resp.RawID)
+	if errors.Is(err, domain.ErrPasskeyNotFound) {
+		return uuid.Nil, domain.ErrInvalidPasskey
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(resp.Response.UserHandle) > 0 && string(resp.Response.UserHandle) != strconv.Itoa(passkey.UserId) {
+		return uuid.Nil, domain.ErrInvalidPasskey
+	}
+
+	credential := webauthn.Credential{
+		ID:        passkey.CredentialId,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	}
+	signCount, err := b.relyingParty().VerifyAssertion(challenge, credential, resp)
+	if err != nil {
+		log.Printf("Error with passkey login:%s", err)
+		return uuid.Nil, domain.ErrInvalidPasskey
+	}
+	err = b.repo.UpdatePasskeyUsage(passkey.Id, signCount, time.Now())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	users, err := b.repo.GetUserById(passkey.UserId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(users) == 0 {
+		return uuid.Nil, domain.ErrInvalidUser
+	}
+	return b.createSession(users[0], client)
+}
+
+func (b *Business) GetPasskeys(userID int) ([]domain.Passkey, error) {
+	return b.repo.GetPasskeys(userID)
+}
+
+func (b *Business) RenamePasskey(userID, id int, name string) error {
+	return b.repo.RenamePasskey(userID, id, passkeyName(name))
+}
+
+func (b *Business) DeletePasskey(userID, id int) error {
+	return b.repo.DeletePasskey(userID, id)
+}
+
+func passkeyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return passkeyDefaultName
+	}
+	if len([]rune(name)) > passkeyNameMaxLength {
+		name = string([]rune(name)[:passkeyNameMaxLength])
+	}
+	return name
+}
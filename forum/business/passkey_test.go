@@ -0,0 +1,263 @@
+package business
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"forum/forum/domain"
+	"forum/forum/webauthn"
+)
+
+// softAuthenticator is an ES256 authenticator in software, answering
+// ceremonies as a browser and platform authenticator would.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, credentialID: id}
+}
+
+func (a *softAuthenticator) register(t *testing.T, opts webauthn.CreationOptions, origin string) webauthn.AttestationResponse {
+	t.Helper()
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	coseKey := cborMap(
+		cborInt(1), cborInt(2),
+		cborInt(3), cborInt(webauthn.AlgES256),
+		cborInt(-1), cborInt(1),
+		cborInt(-2), cborBytes(x),
+		cborInt(-3), cborBytes(y),
+	)
+	authData := a.authenticatorData(opts.RP.ID, 0x45)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, coseKey...)
+
+	var resp webauthn.AttestationResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credentialID)
+	resp.RawID = a.credentialID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = clientDataJSON(t, "webauthn.create", opts.Challenge, origin)
+	resp.Response.AttestationObject = cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData),
+	)
+	return resp
+}
+
+func (a *softAuthenticator) login(t *testing.T, opts webauthn.RequestOptions, origin string, userID int) webauthn.AssertionResponse {
+	t.Helper()
+	a.signCount++
+	authData := a.authenticatorData(opts.RPID, 0x05)
+	clientData := clientDataJSON(t, "webauthn.get", opts.Challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp webauthn.AssertionResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credentialID)
+	resp.RawID = a.credentialID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = clientData
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = signature
+	resp.Response.UserHandle = []byte(strconv.Itoa(userID))
+	return resp
+}
+
+// authenticatorData is the fixed header: relying party id hash, flags and
+// signature counter.
+func (a *softAuthenticator) authenticatorData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func clientDataJSON(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// The CBOR encoding of the few items attestation objects and COSE keys use.
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	}
+	return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap encodes the alternating keys and values given.
+func cborMap(items ...[]byte) []byte {
+	m := cborHead(5, uint64(len(items)/2))
+	for _, item := range items {
+		m = append(m, item...)
+	}
+	return m
+}
+
+const testOrigin = "http://localhost:8080"
+
+func TestPasskeyCeremonies(t *testing.T) {
+	b := newTestBusiness(t, DefaultConfig())
+	user := addTestUser(t, b, "alice", "alice@example.com", true)
+	auth := newSoftAuthenticator(t)
+
+	// Registration.
+	creation, err := b.BeginPasskeyRegistration(user.UserId, user.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongOrigin := auth.register(t, creation, "http://evil.example")
+	err = b.FinishPasskeyRegistration(user.UserId, "laptop", wrongOrigin)
+	if !errors.Is(err, domain.ErrInvalidPasskey) {
+		t.Fatalf("registration from another origin: got %v, want ErrInvalidPasskey", err)
+	}
+
+	creation, err = b.BeginPasskeyRegistration(user.UserId, user.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registration := auth.register(t, creation, testOrigin)
+	err = b.FinishPasskeyRegistration(user.UserId, "laptop", registration)
+	if err != nil {
+		t.Fatalf("registration: %v", err)
+	}
+	err = b.FinishPasskeyRegistration(user.UserId, "laptop", registration)
+	if !errors.Is(err, domain.ErrInvalidPasskey) {
+		t.Fatalf("replayed registration: got %v, want ErrInvalidPasskey", err)
+	}
+	passkeys, err := b.GetPasskeys(user.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passkeys) != 1 || passkeys[0].Name != "laptop" {
+		t.Fatalf("passkeys after registration: %+v", passkeys)
+	}
+
+	// Sign-in.
+	request, err := b.BeginPasskeyLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertion := auth.login(t, request, testOrigin, user.UserId)
+	sessionID, err := b.FinishPasskeyLogin(assertion, testClient)
+	if err != nil {
+		t.Fatalf("sign-in: %v", err)
+	}
+	session, err := b.Session(sessionID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.UserId != user.UserId {
+		t.Fatalf("signed in as user %d, want %d", session.UserId, user.UserId)
+	}
+	counted := auth.signCount
+
+	_, err = b.FinishPasskeyLogin(assertion, testClient)
+	if !errors.Is(err, domain.ErrInvalidPasskey) {
+		t.Fatalf("replayed sign-in: got %v, want ErrInvalidPasskey", err)
+	}
+
+	tests := []struct {
+		name   string
+		answer func(request webauthn.RequestOptions) webauthn.AssertionResponse
+	}{
+		{"wrong origin", func(request webauthn.RequestOptions) webauthn.AssertionResponse {
+			return auth.login(t, request, "http://evil.example", user.UserId)
+		}},
+		{"wrong challenge", func(request webauthn.RequestOptions) webauthn.AssertionResponse {
+			request.Challenge = append([]byte(nil), request.Challenge...)
+			request.Challenge[0] ^= 0xff
+			return auth.login(t, request, testOrigin, user.UserId)
+		}},
+		{"sign count not increasing", func(request webauthn.RequestOptions) webauthn.AssertionResponse {
+			auth.signCount = counted - 1
+			return auth.login(t, request, testOrigin, user.UserId)
+		}},
+		{"bad signature", func(request webauthn.RequestOptions) webauthn.AssertionResponse {
+			resp := auth.login(t, request, testOrigin, user.UserId)
+			resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff
+			return resp
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := b.BeginPasskeyLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = b.FinishPasskeyLogin(tt.answer(request), testClient)
+			if !errors.Is(err, domain.ErrInvalidPasskey) {
+				t.Fatalf("got %v, want ErrInvalidPasskey", err)
+			}
+		})
+	}
+
+	// The failures above left the stored counter alone, so the authenticator
+	// still signs in.
+	request, err = b.BeginPasskeyLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.FinishPasskeyLogin(auth.login(t, request, testOrigin, user.UserId), testClient)
+	if err != nil {
+		t.Fatalf("sign-in after failures: %v", err)
+	}
+}
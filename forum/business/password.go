@@ -0,0 +1,104 @@
+package business
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"forum/forum/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RequestPasswordReset mails a reset link to the owner of email. Unknown
+// addresses are silently ignored so the form cannot be used to probe for
+// registered accounts.
+func (b *Business) RequestPasswordReset(email string) error {
+	user, err := b.repo.GetUserByEmail(email)
+	if errors.Is(err, domain.ErrInvalidUser) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return b.sendPasswordReset(user)
+}
+
+func (b *Business) sendPasswordReset(user domain.User) error {
+	token, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = b.repo.SavePasswordReset(domain.PasswordReset{
+		UserId:         user.UserId,
+		TokenHash:      hash,
+		CreationDate:   now,
+		ExpirationDate: now.Add(b.config.PasswordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := b.config.BaseURL + "/reset?token=" + url.QueryEscape(token)
+	return b.mailer.Send(domain.Mail{
+		To:      user.Email,
+		Subject: "Reset your Forum password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your Forum account.\n"+
+			"Open the link below within %s to choose a new password:\n\n%s\n\n"+
+			"If it was not you, you can ignore this message.\n",
+			user.Username, b.config.PasswordResetTTL, link),
+	})
+}
+
+// CheckPasswordResetToken reports whether token can still be used.
+func (b *Business) CheckPasswordResetToken(token string) error {
+	_, err := b.passwordReset(token)
+	return err
+}
+
+// ResetPassword sets a new password using a reset token. The token is
+// consumed and every session of the user is invalidated.
+func (b *Business) ResetPassword(token, password string) error {
+	reset, err := b.passwordReset(token)
+	if err != nil {
+		return err
+	}
+	if len(password) < 6 || len(password) > 30 {
+		return domain.ErrInvalidPassword
+	}
+	err = b.repo.ConsumePasswordReset(reset.Id)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	err = b.repo.UpdatePassword(reset.UserId, string(hashedPassword), nil)
+	if err != nil {
+		return err
+	}
+	err = b.repo.DeletePasswordResets(reset.UserId)
+	if err != nil {
+		return err
+	}
+	return b.repo.InvalidateSessions(reset.UserId, nil)
+}
+
+func (b *Business) passwordReset(token string) (domain.PasswordReset, error) {
+	if token == "" {
+		return domain.PasswordReset{}, domain.ErrInvalidResetToken
+	}
+	reset, err := b.repo.GetPasswordReset(hashToken(token))
+	if err != nil {
+		return domain.PasswordReset{}, err
+	}
+	if time.Now().After(reset.ExpirationDate) {
+		return domain.PasswordReset{}, domain.ErrInvalidResetToken
+	}
+	return reset, nil
+}
@@ -0,0 +1,307 @@
+package business
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+)
+
+const maxReportDetails = 1000
+
+// Report flags a post, or one of its comments when commentID is not 0, for
+// the moderators. A user can have one open report per item.
+func (b *Business) Report(actor *domain.Session, postID, commentID int, reason domain.ReportReason, details string) error {
+	err := b.Authorize(actor, domain.ActionReport, 0)
+	if err != nil {
+		return err
+	}
+	if !validReason(reason) {
+		return domain.ErrInvalidReport
+	}
+	details = strings.TrimSpace(details)
+	if len(details) > maxReportDetails {
+		details = details[:maxReportDetails]
+	}
+
+	if commentID != 0 {
+		comment, err := b.repo.GetCommentByID(commentID)
+		if err != nil {
+			return err
+		}
+		postID = comment.PostId
+	} else {
+		_, err = b.repo.GetPostByID(postID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.repo.SaveReport(domain.Report{
+		ReporterId:   actor.UserId,
+		PostId:       postID,
+		CommentId:    commentID,
+		Reason:       reason,
+		Details:      details,
+		CreationDate: time.Now(),
+	})
+}
+
+func validReason(reason domain.ReportReason) bool {
+	for _, r := range domain.ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// GetModerationQueue returns the reported items, the one waiting longest
+// first, with the content they are about.
+func (b *Business) GetModerationQueue(actor *domain.Session) ([]domain.ModerationItem, error) {
+	err := b.Authorize(actor, domain.ActionModerate, 0)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := b.repo.GetOpenReports()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []domain.ModerationItem
+	index := make(map[[2]int]int)
+	for _, report := range reports {
+		key := [2]int{report.PostId, report.CommentId}
+		i, ok := index[key]
+		if !ok {
+			i = len(items)
+			index[key] = i
+			items = append(items, domain.ModerationItem{PostId: report.PostId, CommentId: report.CommentId})
+		}
+		items[i].Reports = append(items[i].Reports, report)
+	}
+
+	for i := range items {
+		items[i].Post, items[i].Comment, err = b.reportedContent(items[i].PostId, items[i].CommentId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// reportedContent loads a reported item. Content that no longer exists is
+// returned as nil.
+func (b *Business) reportedContent(postID, commentID int) (*domain.Posts, *domain.Comments, error) {
+	var post *domain.Posts
+	p, err := b.repo.GetPostByID(postID)
+	if err == nil {
+		post = &p
+	} else if !errors.Is(err, domain.ErrPostNotFound) {
+		return nil, nil, err
+	}
+	if commentID == 0 {
+		return post, nil, nil
+	}
+
+	c, err := b.repo.GetCommentByID(commentID)
+	if errors.Is(err, domain.ErrCommentNotFound) {
+		return post, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, &c, nil
+}
+
+// Moderate resolves the open reports on an item:
+//
+//   - dismiss closes them without touching the content,
+//   - hide takes the content out of listings; its author and moderators can
+//     still open it,
+//   - delete removes the content,
+//   - warn emails its author, including note.
+//
+// Each reporter is told the outcome by email, and the decision is recorded in
+// the audit log with note as its reason.
+func (b *Business) Moderate(actor *domain.Session, postID, commentID int, action domain.ModerationAction, note string) error {
+	err := b.Authorize(actor, domain.ActionModerate, 0)
+	if err != nil {
+		return err
+	}
+	reports, err := b.repo.GetOpenReportsFor(postID, commentID)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return domain.ErrNoOpenReports
+	}
+	post, comment, err := b.reportedContent(postID, commentID)
+	if err != nil {
+		return err
+	}
+	gone := post == nil || (commentID != 0 && comment == nil)
+	note = strings.TrimSpace(note)
+	if _, ok := moderationAudits[action]; !ok {
+		return fmt.Errorf("unknown moderation action %q", action)
+	}
+	if gone && (action == domain.ModerationHide || action == domain.ModerationWarn) {
+		return notFound(commentID)
+	}
+	entry, err := moderationEntry(actor, action, postID, commentID, post, comment, note)
+	if err != nil {
+		return err
+	}
+
+	// The entry is written along with the change to the content, or along
+	// with the resolution of the reports when the content is left as it is.
+	resolution := &entry
+	switch action {
+	case domain.ModerationHide:
+		if commentID != 0 {
+			err = b.repo.SetCommentHidden(commentID, true, entry)
+		} else {
+			err = b.repo.SetPostHidden(postID, true, entry)
+		}
+		resolution = nil
+	case domain.ModerationDelete:
+		if gone {
+			break
+		}
+		if commentID != 0 {
+			err = b.deleteComment(actor, commentID, true, note)
+		} else {
+			err = b.deletePost(actor, postID, true, note)
+		}
+		resolution = nil
+	case domain.ModerationWarn:
+		authorID := post.UserId
+		if comment != nil {
+			authorID = comment.UserId
+		}
+		err = b.sendWarning(authorID, describeContent(post, comment), reports, note)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = b.repo.ResolveReports(postID, commentID, action, actor.UserId, time.Now(), resolution)
+	if err != nil {
+		return err
+	}
+	b.notifyReporters(reports, describeContent(post, comment), action)
+	return nil
+}
+
+var moderationAudits = map[domain.ModerationAction][2]domain.AuditAction{
+	domain.ModerationDismiss: {domain.AuditDismissReports, domain.AuditDismissReports},
+	domain.ModerationHide:    {domain.AuditHidePost, domain.AuditHideComment},
+	domain.ModerationDelete:  {domain.AuditDeletePost, domain.AuditDeleteComment},
+	domain.ModerationWarn:    {domain.AuditWarnAuthor, domain.AuditWarnAuthor},
+}
+
+// moderationEntry makes the audit entry of a moderation decision on a post,
+// or on a comment when commentID is not 0, with the content as it was
+// before.
+func moderationEntry(actor *domain.Session, action domain.ModerationAction, postID, commentID int, post *domain.Posts, comment *domain.Comments, note string) (domain.AuditEntry, error) {
+	audits := moderationAudits[action]
+	if commentID != 0 {
+		var before any
+		if comment != nil {
+			before = comment
+		}
+		return auditEntry(actor, audits[1], domain.TargetComment, commentID, before, note)
+	}
+	var before any
+	if post != nil {
+		before = post
+	}
+	return auditEntry(actor, audits[0], domain.TargetPost, postID, before, note)
+}
+
+func notFound(commentID int) error {
+	if commentID != 0 {
+		return domain.ErrCommentNotFound
+	}
+	return domain.ErrPostNotFound
+}
+
+func describeContent(post *domain.Posts, comment *domain.Comments) string {
+	switch {
+	case post == nil:
+		return "a post"
+	case comment != nil:
+		return fmt.Sprintf("a comment on %q", post.Title)
+	default:
+		return fmt.Sprintf("the post %q", post.Title)
+	}
+}
+
+func (b *Business) sendWarning(userID int, content string, reports []domain.Report, note string) error {
+	users, err := b.repo.GetUserById(userID)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return domain.ErrInvalidUser
+	}
+
+	reasons := make([]string, 0, len(reports))
+	seen := make(map[domain.ReportReason]bool)
+	for _, report := range reports {
+		if !seen[report.Reason] {
+			seen[report.Reason] = true
+			reasons = append(reasons, string(report.Reason))
+		}
+	}
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"Other members reported %s you wrote (%s). A moderator reviewed it and asks you to follow the forum rules; "+
+		"repeated problems can lead to your account being restricted.\n",
+		users[0].Username, content, strings.Join(reasons, ", "))
+	if note != "" {
+		body += "\nMessage from the moderator:\n\n" + note + "\n"
+	}
+	return b.mailer.Send(domain.Mail{
+		To:      users[0].Email,
+		Subject: "A warning from the Forum moderators",
+		Body:    body,
+	})
+}
+
+var moderationOutcomes = map[domain.ModerationAction]string{
+	domain.ModerationDismiss: "found that it does not break the forum rules",
+	domain.ModerationHide:    "has hidden it",
+	domain.ModerationDelete:  "has removed it",
+	domain.ModerationWarn:    "has warned its author",
+}
+
+// notifyReporters tells each reporter how their report was handled. The
+// reports are resolved already, so failures are only logged.
+func (b *Business) notifyReporters(reports []domain.Report, content string, action domain.ModerationAction) {
+	notified := make(map[int]bool)
+	for _, report := range reports {
+		if notified[report.ReporterId] {
+			continue
+		}
+		notified[report.ReporterId] = true
+
+		users, err := b.repo.GetUserById(report.ReporterId)
+		if err != nil || len(users) == 0 {
+			log.Printf("Error notifying reporter %d:%v", report.ReporterId, err)
+			continue
+		}
+		err = b.mailer.Send(domain.Mail{
+			To:      users[0].Email,
+			Subject: "Your report has been reviewed",
+			Body: fmt.Sprintf("Hello %s,\n\n"+
+				"Thank you for reporting %s. A moderator %s.\n",
+				users[0].Username, content, moderationOutcomes[action]),
+		})
+		if err != nil {
+			log.Printf("Error notifying reporter %d:%s", report.ReporterId, err)
+		}
+	}
+}
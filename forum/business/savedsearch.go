@@ -0,0 +1,205 @@
+package business
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+)
+
+const savedSearchNameMaxLength = 50
+
+// SaveSearch keeps a search query of the user to be told about new posts
+// matching it. Only posts written from now on are matched; the name
+// defaults to the query.
+func (b *Business) SaveSearch(userID int, name, query string, notify, digest bool) error {
+	query = strings.TrimSpace(query)
+	_, err := parseSearch(query)
+	if err != nil {
+		return err
+	}
+	n, err := b.repo.CountSavedSearches(userID)
+	if err != nil {
+		return err
+	}
+	if n >= domain.MaxSavedSearches {
+		return domain.ErrTooManySavedSearches
+	}
+	latest, err := b.repo.LatestPostID()
+	if err != nil {
+		return err
+	}
+	return b.repo.SaveSavedSearch(domain.SavedSearch{
+		UserId:       userID,
+		Name:         savedSearchName(name, query),
+		Query:        query,
+		Notify:       notify,
+		Digest:       digest,
+		LastPostId:   latest,
+		CreationDate: time.Now(),
+	})
+}
+
+func savedSearchName(name, query string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = query
+	}
+	if len([]rune(name)) > savedSearchNameMaxLength {
+		name = string([]rune(name)[:savedSearchNameMaxLength])
+	}
+	return name
+}
+
+func (b *Business) GetSavedSearches(userID int) ([]domain.SavedSearch, error) {
+	return b.repo.GetSavedSearches(userID)
+}
+
+// UpdateSavedSearch changes how the user hears about new posts matching one
+// of their saved searches.
+func (b *Business) UpdateSavedSearch(userID, id int, notify, digest bool) error {
+	return b.repo.UpdateSavedSearch(userID, id, notify, digest)
+}
+
+func (b *Business) DeleteSavedSearch(userID, id int) error {
+	return b.repo.DeleteSavedSearch(userID, id)
+}
+
+// GetSearchAlerts retrieves a page of the new posts found by the saved
+// searches of an owner.
+func (b *Business) GetSearchAlerts(ownerID int, req domain.PageRequest) ([]domain.SearchAlert, domain.Page, error) {
+	return b.repo.GetSearchAlerts(ownerID, req)
+}
+
+// wakeSearchAlerts tells the matcher there are new posts, without waiting
+// for it: one wake-up pending is enough for any number of posts.
+func (b *Business) wakeSearchAlerts() {
+	select {
+	case b.newPosts <- struct{}{}:
+	default:
+	}
+}
+
+// StartSearchAlerts matches saved searches against new posts in the
+// background, whenever a post is written and every SearchAlertInterval, and
+// mails the digests every SearchDigestInterval.
+// The returned function stops the matcher.
+func (b *Business) StartSearchAlerts() func() {
+	ticker := time.NewTicker(b.config.SearchAlertInterval)
+	digest := time.NewTicker(b.config.SearchDigestInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-b.newPosts:
+				b.matchSavedSearches()
+			case <-ticker.C:
+				b.matchSavedSearches()
+			case <-digest.C:
+				b.matchSavedSearches()
+				b.sendSearchDigests()
+			case <-done:
+				ticker.Stop()
+				digest.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// matchSavedSearches records the posts written since each saved search was
+// last matched that it finds.
+func (b *Business) matchSavedSearches() {
+	latest, err := b.repo.LatestPostID()
+	if err != nil {
+		log.Printf("Error matching saved searches:%s", err)
+		return
+	}
+	searches, err := b.repo.GetAlertingSearches()
+	if err != nil {
+		log.Printf("Error matching saved searches:%s", err)
+		return
+	}
+	now := time.Now()
+	for _, search := range searches {
+		if search.LastPostId >= latest {
+			continue
+		}
+		var matches []int
+		filter, err := parseSearch(search.Query)
+		if err == nil {
+			matches, err = b.repo.MatchNewPosts(filter, search.UserId, search.LastPostId, latest)
+		}
+		if err != nil {
+			// The search stays where it was and is tried again with the
+			// next posts, in case the failure passes, as when search is
+			// unavailable until the server is rebuilt with FTS5.
+			log.Printf("Error matching saved search %d:%s", search.Id, err)
+			continue
+		}
+		err = b.repo.SaveSearchAlerts(search, matches, latest, now)
+		if err != nil {
+			log.Printf("Error saving alerts of saved search %d:%s", search.Id, err)
+		}
+	}
+}
+
+// sendSearchDigests mails each user the posts their saved searches found
+// since the last digest. Users whose address is not verified keep theirs
+// until it is, and banned users until they are unbanned.
+func (b *Business) sendSearchDigests() {
+	alerts, err := b.repo.GetPendingAlerts()
+	if err != nil {
+		log.Printf("Error collecting search digests:%s", err)
+		return
+	}
+	for start := 0; start < len(alerts); {
+		end := start + 1
+		for end < len(alerts) && alerts[end].OwnerId == alerts[start].OwnerId {
+			end++
+		}
+		err = b.sendSearchDigest(alerts[start].OwnerId, alerts[start:end])
+		if err != nil {
+			log.Printf("Error sending search digest to user %d:%s", alerts[start].OwnerId, err)
+		}
+		start = end
+	}
+}
+
+func (b *Business) sendSearchDigest(ownerID int, alerts []domain.SearchAlert) error {
+	users, err := b.repo.GetUserById(ownerID)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return domain.ErrInvalidUser
+	}
+	user := users[0]
+
+	var body strings.Builder
+	search := 0
+	ids := make([]int, len(alerts))
+	for i, alert := range alerts {
+		if alert.SearchId != search {
+			search = alert.SearchId
+			fmt.Fprintf(&body, "\n%s:\n", alert.SearchName)
+		}
+		fmt.Fprintf(&body, "  %s by %s\n  %s/post/?id=%d\n", alert.Title, alert.Username, b.config.BaseURL, alert.PostId)
+		ids[i] = alert.Id
+	}
+	err = b.mailer.Send(domain.Mail{
+		To:      user.Email,
+		Subject: "New posts matching your saved searches",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"These posts were written since your last digest:\n%s\n"+
+			"You can change which searches are sent to you at %s/search/saved\n",
+			user.Username, body.String(), b.config.BaseURL),
+	})
+	if err != nil {
+		return err
+	}
+	return b.repo.ClearPendingAlerts(ids)
+}
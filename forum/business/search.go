@@ -0,0 +1,51 @@
+package business
+
+import (
+	"strings"
+
+	"forum/forum/domain"
+	"forum/forum/searchquery"
+)
+
+// Search lists a page of the posts, or with query.Kind SearchComments the
+// comments, viewer may see that match the query, best match first.
+func (b *Business) Search(viewer *domain.Session, query domain.SearchQuery, req domain.PageRequest) ([]domain.SearchResult, domain.Page, error) {
+	filter, err := parseSearch(query.Text)
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	if query.Kind == domain.SearchComments {
+		return b.repo.SearchComments(filter, viewerID(viewer), req)
+	}
+	return b.repo.SearchPosts(filter, viewerID(viewer), req)
+}
+
+// parseSearch parses a search query into the filter it describes, with its
+// tags written the way posts store them.
+func parseSearch(text string) (domain.SearchFilter, error) {
+	text = strings.TrimSpace(text)
+	if len(text) > domain.MaxSearchLength {
+		return domain.SearchFilter{}, domain.ErrSearchTooLong
+	}
+	filter, err := searchquery.Parse(text)
+	if err != nil {
+		return domain.SearchFilter{}, err
+	}
+	if filter.IsZero() {
+		return domain.SearchFilter{}, domain.ErrEmptySearch
+	}
+	for i, tag := range filter.Tags {
+		name, ok := normalizeTag(tag)
+		if !ok {
+			return domain.SearchFilter{}, domain.ErrInvalidTag
+		}
+		filter.Tags[i] = name
+	}
+	return filter, nil
+}
+
+// RebuildSearchIndex indexes every post and comment again, for databases
+// whose index is missing or out of date.
+func (b *Business) RebuildSearchIndex() error {
+	return b.repo.RebuildSearchIndex()
+}
@@ -0,0 +1,117 @@
+package business
+
+import (
+	"log"
+	"time"
+
+	"forum/forum/domain"
+)
+
+// sessionRenewThreshold avoids a database write on every request: the expiry
+// is only moved when it would advance by more than this.
+const sessionRenewThreshold = time.Minute
+
+func (b *Business) Session(sessionID string) (*domain.Session, error) {
+	session, err := b.repo.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	deadline := session.CreationDate.Add(b.config.SessionMaxLifetime)
+	if session.ExpiritionDate.IsZero() || now.After(session.ExpiritionDate) || now.After(deadline) {
+		return nil, domain.ErrSessionExpired
+	}
+
+	expiration := b.sessionExpiration(session.CreationDate, now)
+	if expiration.Sub(session.ExpiritionDate) > sessionRenewThreshold {
+		err = b.repo.RenewSession(session.SessionId, now, expiration)
+		if err != nil {
+			return nil, err
+		}
+		session.LastSeen = now
+		session.ExpiritionDate = expiration
+	}
+
+	return &session, nil
+}
+
+// Logout destroys the session so its identifier can no longer be replayed.
+func (b *Business) Logout(sessionID string) error {
+	return b.repo.DeleteSession(sessionID)
+}
+
+// LogoutEverywhere destroys every session of the user owning sessionID.
+func (b *Business) LogoutEverywhere(sessionID string) error {
+	session, err := b.repo.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return b.repo.InvalidateSessions(session.UserId, nil)
+}
+
+// GetSessions lists every active device the user is signed in on.
+func (b *Business) GetSessions(userID int) ([]domain.Session, error) {
+	sessions, err := b.repo.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := sessions[:0]
+	for _, s := range sessions {
+		if s.ExpiritionDate.After(now) {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession signs out one of the user's devices.
+func (b *Business) RevokeSession(userID, id int) error {
+	return b.repo.DeleteUserSession(userID, id)
+}
+
+// RevokeOtherSessions signs out every device of the user except the current one.
+func (b *Business) RevokeOtherSessions(userID int, sessionID string) error {
+	return b.repo.InvalidateOtherSessions(userID, sessionID)
+}
+
+// sessionExpiration returns the sliding expiry for a session seen at now,
+// bounded by the absolute lifetime counted from its creation.
+func (b *Business) sessionExpiration(created, now time.Time) time.Time {
+	expiration := now.Add(b.config.SessionIdleTimeout)
+	deadline := created.Add(b.config.SessionMaxLifetime)
+	if expiration.After(deadline) {
+		return deadline
+	}
+	return expiration
+}
+
+// StartSessionReaper periodically deletes expired sessions and stale login
+// throttles in the background.
+// The returned function stops the reaper.
+func (b *Business) StartSessionReaper() func() {
+	ticker := time.NewTicker(b.config.SessionReapInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				b.purgeThrottles()
+				n, err := b.repo.DeleteExpiredSessions(time.Now())
+				if err != nil {
+					log.Printf("Error purging sessions:%s", err)
+					continue
+				}
+				if n > 0 {
+					log.Printf("Purged %d expired sessions", n)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,195 @@
+package business
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"forum/forum/domain"
+)
+
+const maxTagSuggestions = 10
+
+// normalizeTag turns what a user typed as a tag into its stored form: lower
+// case, without a leading #, with a single - wherever there were spaces,
+// dashes or underscores. It reports false for anything else.
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(tag) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			dash = true
+		default:
+			return "", false
+		}
+	}
+	name := b.String()
+	return name, name != "" && utf8.RuneCountInString(name) <= domain.MaxTagLength
+}
+
+// cleanTags normalizes the tags of a post, drops blanks and repeats and
+// refuses invalid and banned tags or too many of them.
+func (b *Business) cleanTags(tags []string) ([]string, error) {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		name, ok := normalizeTag(tag)
+		if !ok {
+			return nil, domain.ErrInvalidTag
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		cleaned = append(cleaned, name)
+	}
+	if len(cleaned) > domain.MaxTagsPerPost {
+		return nil, domain.ErrTooManyTags
+	}
+	for _, name := range cleaned {
+		tag, err := b.repo.GetTag(name)
+		if errors.Is(err, domain.ErrTagNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tag.Banned {
+			return nil, &domain.BannedTagError{Tag: name}
+		}
+	}
+	return cleaned, nil
+}
+
+// visibleTag looks up a tag by what a user typed; banned tags are not found.
+func (b *Business) visibleTag(name string) (domain.Tag, error) {
+	name, ok := normalizeTag(name)
+	if !ok {
+		return domain.Tag{}, domain.ErrTagNotFound
+	}
+	tag, err := b.repo.GetTag(name)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	if tag.Banned {
+		return domain.Tag{}, domain.ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// GetPostsByTag lists a page of the posts viewer may see under the tag.
+func (b *Business) GetPostsByTag(viewer *domain.Session, name string, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	tag, err := b.visibleTag(name)
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	return b.repo.GetPostsByTag(tag.Id, viewerID(viewer), req)
+}
+
+// GetTagCloud returns the tags in use on posts viewer may see, by name.
+func (b *Business) GetTagCloud(viewer *domain.Session) ([]domain.Tag, error) {
+	tags, err := b.repo.GetTags(viewerID(viewer))
+	if err != nil {
+		return nil, err
+	}
+	var cloud []domain.Tag
+	for _, t := range tags {
+		if !t.Banned && t.PostCount > 0 {
+			cloud = append(cloud, t)
+		}
+	}
+	return cloud, nil
+}
+
+// GetTags returns every tag, banned and unused ones included, for
+// moderators managing them.
+func (b *Business) GetTags(actor *domain.Session) ([]domain.Tag, error) {
+	err := b.Authorize(actor, domain.ActionManageTags, 0)
+	if err != nil {
+		return nil, err
+	}
+	return b.repo.GetTags(actor.UserId)
+}
+
+// SuggestTags completes a partly typed tag with the most used tags starting
+// with it.
+func (b *Business) SuggestTags(prefix string) ([]string, error) {
+	prefix, ok := normalizeTag(prefix)
+	if !ok {
+		return nil, nil
+	}
+	return b.repo.SearchTags(prefix, maxTagSuggestions)
+}
+
+// BanTag stops or, with banned false, allows the tag on posts again. Posts
+// keep a banned tag but it is not shown.
+func (b *Business) BanTag(actor *domain.Session, name string, banned bool) error {
+	tag, err := b.managedTag(actor, name)
+	if err != nil {
+		return err
+	}
+	action := domain.AuditBanTag
+	if !banned {
+		action = domain.AuditUnbanTag
+	}
+	entry, err := auditEntry(actor, action, domain.TargetTag, tag.Id, tag, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.SetTagBanned(tag.Id, banned, entry)
+}
+
+// MergeTags retags every post tagged from with into, creating into if it is
+// new, and deletes from.
+func (b *Business) MergeTags(actor *domain.Session, from, into string) error {
+	fromTag, err := b.managedTag(actor, from)
+	if err != nil {
+		return err
+	}
+	into, ok := normalizeTag(into)
+	if !ok {
+		return domain.ErrInvalidTag
+	}
+	if into == fromTag.Name {
+		return domain.ErrMergeSameTag
+	}
+	intoTag, err := b.repo.GetTag(into)
+	if err != nil && !errors.Is(err, domain.ErrTagNotFound) {
+		return err
+	}
+	renamed := err != nil
+	entry, err := auditEntry(actor, domain.AuditMergeTag, domain.TargetTag, fromTag.Id, fromTag, "merged into "+into)
+	if err != nil {
+		return err
+	}
+	if renamed {
+		return b.repo.RenameTag(fromTag.Id, into, entry)
+	}
+	return b.repo.MergeTags(fromTag.Id, intoTag.Id, entry)
+}
+
+// managedTag checks that actor may manage tags and returns the tag named
+// name.
+func (b *Business) managedTag(actor *domain.Session, name string) (domain.Tag, error) {
+	err := b.Authorize(actor, domain.ActionManageTags, 0)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	name, ok := normalizeTag(name)
+	if !ok {
+		return domain.Tag{}, domain.ErrTagNotFound
+	}
+	return b.repo.GetTag(name)
+}
@@ -0,0 +1,152 @@
+package business
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+)
+
+func loginUserKey(username string) string {
+	return "login_user:" + strings.ToLower(username)
+}
+
+func loginIPKey(client domain.Client) string {
+	return "login_ip:" + client.IP
+}
+
+func registerIPKey(client domain.Client) string {
+	return "register_ip:" + client.IP
+}
+
+// checkThrottle fails with a *domain.ThrottleError if any of keys is
+// currently blocked.
+func (b *Business) checkThrottle(keys ...string) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		throttle, err := b.repo.GetThrottle(key)
+		if err != nil {
+			return err
+		}
+		if throttle.BlockedUntil.After(now) && throttle.BlockedUntil.Sub(now) > wait {
+			wait = throttle.BlockedUntil.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return &domain.ThrottleError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// backoff is the wait after the given number of failures: nothing for the
+// first free ones, then doubling from LoginBackoffBase.
+func (b *Business) backoff(failures, free int) time.Duration {
+	if failures <= free {
+		return 0
+	}
+	wait := b.config.LoginBackoffBase
+	for i := free + 1; i < failures && wait < b.config.LoginBackoffMax; i++ {
+		wait *= 2
+	}
+	if wait > b.config.LoginBackoffMax {
+		wait = b.config.LoginBackoffMax
+	}
+	return wait
+}
+
+// loginFailed records a failed password or second factor for username and
+// the client's IP address.
+func (b *Business) loginFailed(username string, client domain.Client) error {
+	now := time.Now()
+	resetBefore := now.Add(-b.config.FailureWindow)
+
+	userKey := loginUserKey(username)
+	failures, err := b.repo.CountThrottleEvent(userKey, now, resetBefore)
+	if err != nil {
+		return err
+	}
+	wait := b.backoff(failures, b.config.LoginFreeAttempts)
+	if b.config.LockoutThreshold > 0 && failures >= b.config.LockoutThreshold && wait < b.config.LockoutDuration {
+		wait = b.config.LockoutDuration
+	}
+	if wait > 0 {
+		err = b.repo.BlockThrottle(userKey, now.Add(wait))
+		if err != nil {
+			return err
+		}
+	}
+	if failures == b.config.LockoutThreshold {
+		b.notifyLockout(username, failures, client)
+	}
+
+	ipKey := loginIPKey(client)
+	failures, err = b.repo.CountThrottleEvent(ipKey, now, resetBefore)
+	if err != nil {
+		return err
+	}
+	wait = b.backoff(failures, b.config.IPFreeAttempts)
+	if wait > 0 {
+		return b.repo.BlockThrottle(ipKey, now.Add(wait))
+	}
+	return nil
+}
+
+// loginSucceeded forgets the failures of username. Failures of the IP address
+// are kept, so that signing in to one's own account does not reset the
+// budget for guessing other passwords.
+func (b *Business) loginSucceeded(username string) error {
+	return b.repo.DeleteThrottle(loginUserKey(username))
+}
+
+func (b *Business) notifyLockout(username string, failures int, client domain.Client) {
+	user, err := b.repo.GetUser(username)
+	if err != nil {
+		// Unknown usernames get locked too, but there is no one to tell.
+		return
+	}
+	err = b.mailer.Send(domain.Mail{
+		To:      user.Email,
+		Subject: "Your Forum account has been locked",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"There have been %d failed attempts to sign in to your account, the last one from %s. "+
+			"To protect it, signing in is blocked for %s.\n\n"+
+			"If this was not you, someone may be guessing your password. You can choose a new one here:\n\n%s\n",
+			user.Username, failures, client.IP, b.config.LockoutDuration, b.config.BaseURL+"/forgot"),
+	})
+	if err != nil {
+		log.Printf("Error sending lockout mail:%s", err)
+	}
+}
+
+// registered counts an account created from the client's IP address and
+// blocks further registrations once the limit is reached.
+func (b *Business) registered(client domain.Client) error {
+	if b.config.RegistrationsPerIP <= 0 {
+		return nil
+	}
+	now := time.Now()
+	key := registerIPKey(client)
+	count, err := b.repo.CountThrottleEvent(key, now, now.Add(-b.config.RegistrationWindow))
+	if err != nil {
+		return err
+	}
+	if count >= b.config.RegistrationsPerIP {
+		return b.repo.BlockThrottle(key, now.Add(b.config.RegistrationWindow))
+	}
+	return nil
+}
+
+// purgeThrottles deletes counters that have not changed for FailureWindow.
+func (b *Business) purgeThrottles() {
+	n, err := b.repo.DeleteStaleThrottles(time.Now().Add(-b.config.FailureWindow))
+	if err != nil {
+		log.Printf("Error purging login throttles:%s", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Purged %d login throttles", n)
+	}
+}
@@ -0,0 +1,264 @@
+package business
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/totp"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	totpIssuer          = "Forum"
+	recoveryCodeCount   = 10
+	loginChallengeTTL   = 5 * time.Minute
+	loginChallengeTries = 5
+)
+
+// recoveryAlphabet leaves out characters that are easily confused.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+func (b *Business) newLoginChallenge(userID int) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	err = b.repo.SaveLoginChallenge(domain.LoginChallenge{
+		UserId:         userID,
+		TokenHash:      hash,
+		ExpirationDate: time.Now().Add(loginChallengeTTL),
+	})
+	return token, err
+}
+
+// CompleteTwoFactorLogin finishes a login started by Login once the user has
+// entered a TOTP code or one of their recovery codes.
+func (b *Business) CompleteTwoFactorLogin(challengeToken, code string, client domain.Client) (uuid.UUID, error) {
+	challenge, err := b.repo.GetLoginChallenge(hashToken(challengeToken))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if time.Now().After(challenge.ExpirationDate) || challenge.Attempts >= loginChallengeTries {
+		err = b.repo.DeleteLoginChallenge(challenge.Id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return uuid.Nil, domain.ErrInvalidLoginChallenge
+	}
+
+	users, err := b.repo.GetUserById(challenge.UserId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(users) == 0 {
+		return uuid.Nil, domain.ErrInvalidUser
+	}
+	user := users[0]
+
+	// Wrong codes count as failed logins, otherwise restarting the login
+	// would give an attacker who knows the password unlimited guesses.
+	err = b.checkThrottle(loginUserKey(user.Username), loginIPKey(client))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = b.verifySecondFactor(challenge.UserId, code)
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		countErr := b.repo.CountLoginChallengeAttempt(challenge.Id)
+		if countErr != nil {
+			return uuid.Nil, countErr
+		}
+		countErr = b.loginFailed(user.Username, client)
+		if countErr != nil {
+			return uuid.Nil, countErr
+		}
+		return uuid.Nil, err
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = b.repo.DeleteLoginChallenge(challenge.Id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = b.loginSucceeded(user.Username)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return b.createSession(user, client)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. Accepted codes cannot be used again.
+func (b *Business) verifySecondFactor(userID int, code string) error {
+	tf, err := b.repo.GetTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled {
+		return domain.ErrTwoFactorNotEnabled
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == totp.Digits {
+		counter, ok := totp.Validate(tf.Secret, code, time.Now(), 1)
+		if !ok {
+			return domain.ErrInvalidTwoFactorCode
+		}
+		return b.repo.UseTwoFactorCounter(userID, counter)
+	}
+	return b.repo.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(code)))
+}
+
+func (b *Business) GetTwoFactorStatus(userID int, username string) (domain.TwoFactorStatus, error) {
+	tf, err := b.repo.GetTwoFactor(userID)
+	if errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		return domain.TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return domain.TwoFactorStatus{}, err
+	}
+	if !tf.Enabled {
+		return domain.TwoFactorStatus{
+			SetupSecret: tf.Secret,
+			SetupURI:    totp.URI(totpIssuer, username, tf.Secret),
+		}, nil
+	}
+
+	left, err := b.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return domain.TwoFactorStatus{}, err
+	}
+	return domain.TwoFactorStatus{
+		Enabled:           true,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// BeginTwoFactorSetup stores a new, not yet enabled TOTP secret for the user.
+func (b *Business) BeginTwoFactorSetup(userID int) error {
+	tf, err := b.repo.GetTwoFactor(userID)
+	if err == nil && tf.Enabled {
+		return nil
+	}
+	if err != nil && !errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		return err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	return b.repo.SaveTwoFactor(domain.TwoFactor{
+		UserId:       userID,
+		Secret:       secret,
+		CreationDate: time.Now(),
+	})
+}
+
+// ConfirmTwoFactorSetup enables TOTP once the user proves their app produces
+// valid codes, and returns a fresh set of recovery codes.
+func (b *Business) ConfirmTwoFactorSetup(userID int, code string) ([]string, error) {
+	tf, err := b.repo.GetTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, domain.ErrInvalidTwoFactorCode
+	}
+	counter, ok := totp.Validate(tf.Secret, code, time.Now(), 1)
+	if !ok {
+		return nil, domain.ErrInvalidTwoFactorCode
+	}
+
+	tf.Enabled = true
+	tf.LastCounter = counter
+	err = b.repo.SaveTwoFactor(tf)
+	if err != nil {
+		return nil, err
+	}
+	return b.newRecoveryCodes(userID)
+}
+
+func (b *Business) DisableTwoFactor(userID int, code string) error {
+	err := b.verifySecondFactor(userID, code)
+	if err != nil {
+		return err
+	}
+	return b.repo.DeleteTwoFactor(userID, nil)
+}
+
+func (b *Business) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	err := b.verifySecondFactor(userID, code)
+	if err != nil {
+		return nil, err
+	}
+	return b.newRecoveryCodes(userID)
+}
+
+// ResetTwoFactor lets an administrator remove the second factor of a user
+// who lost both their authenticator and recovery codes.
+func (b *Business) ResetTwoFactor(actor *domain.Session, username string) error {
+	err := b.Authorize(actor, domain.ActionManageUsers, 0)
+	if err != nil {
+		return err
+	}
+	user, err := b.repo.GetUser(username)
+	if err != nil {
+		return err
+	}
+	entry, err := auditEntry(actor, domain.AuditResetTwoFactor, domain.TargetUser, user.UserId, user, "")
+	if err != nil {
+		return err
+	}
+	return b.repo.DeleteTwoFactor(user.UserId, &entry)
+}
+
+func (b *Business) newRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw, err := randomRecoveryChars(10)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = string(raw[:5]) + "-" + string(raw[5:])
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	err := b.repo.SaveRecoveryCodes(userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// randomRecoveryChars returns n characters drawn uniformly from
+// recoveryAlphabet. Random bytes past the last whole multiple of its length
+// are thrown away, as taking them modulo the length would favour the first
+// characters.
+func randomRecoveryChars(n int) ([]byte, error) {
+	const limit = 256 - 256%len(recoveryAlphabet)
+	chars := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(chars) < n {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range buf {
+			if int(c) < limit && len(chars) < n {
+				chars = append(chars, recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+			}
+		}
+	}
+	return chars, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
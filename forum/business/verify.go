@@ -0,0 +1,92 @@
+package business
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"forum/forum/domain"
+)
+
+func (b *Business) sendVerification(user domain.User) error {
+	token, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = b.repo.SaveEmailVerification(domain.EmailVerification{
+		UserId:         user.UserId,
+		TokenHash:      hash,
+		CreationDate:   now,
+		ExpirationDate: now.Add(b.config.EmailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := b.config.BaseURL + "/verify?token=" + url.QueryEscape(token)
+	return b.mailer.Send(domain.Mail{
+		To:      user.Email,
+		Subject: "Verify your Forum email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Please confirm that this address belongs to you by opening the link below within %s:\n\n%s\n\n"+
+			"If you did not create a Forum account, you can ignore this message.\n",
+			user.Username, b.config.EmailVerificationTTL, link),
+	})
+}
+
+// VerifyEmail marks the owner of token as verified and discards their
+// outstanding verification links.
+func (b *Business) VerifyEmail(token string) error {
+	if token == "" {
+		return domain.ErrInvalidVerifyToken
+	}
+	verification, err := b.repo.GetEmailVerification(hashToken(token))
+	if err != nil {
+		return err
+	}
+	if time.Now().After(verification.ExpirationDate) {
+		return domain.ErrInvalidVerifyToken
+	}
+
+	err = b.repo.SetEmailVerified(verification.UserId)
+	if err != nil {
+		return err
+	}
+	return b.repo.DeleteEmailVerifications(verification.UserId)
+}
+
+// ResendVerification replaces the user's verification links with a new one.
+func (b *Business) ResendVerification(userID int) error {
+	users, err := b.repo.GetUserById(userID)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return domain.ErrInvalidUser
+	}
+	if users[0].EmailVerified {
+		return nil
+	}
+
+	err = b.repo.DeleteEmailVerifications(userID)
+	if err != nil {
+		return err
+	}
+	return b.sendVerification(users[0])
+}
+
+// CheckPermission reports whether the session's user may perform action.
+// Users who have not verified their email are limited to the configured
+// UnverifiedActions.
+func (b *Business) CheckPermission(session *domain.Session, action domain.Action) error {
+	if session.EmailVerified {
+		return nil
+	}
+	for _, a := range b.config.UnverifiedActions {
+		if a == action {
+			return nil
+		}
+	}
+	return domain.ErrEmailNotVerified
+}
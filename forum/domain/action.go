@@ -0,0 +1,35 @@
+package domain
+
+// Action is something a signed-in user does that may be restricted by policy.
+type Action string
+
+const (
+	ActionPost    Action = "post"
+	ActionComment Action = "comment"
+	ActionReact   Action = "react"
+
+	ActionEditPost         Action = "edit_post"
+	ActionDeletePost       Action = "delete_post"
+	ActionEditComment      Action = "edit_comment"
+	ActionDeleteComment    Action = "delete_comment"
+	ActionManageUsers      Action = "manage_users"
+	ActionManageCategories Action = "manage_categories"
+	ActionManageTags       Action = "manage_tags"
+	ActionReport           Action = "report"
+	ActionModerate         Action = "moderate"
+	ActionViewHidden       Action = "view_hidden"
+)
+
+// ForbiddenError is returned when a user may not perform an action. It
+// matches ErrForbidden with errors.Is.
+type ForbiddenError struct {
+	Action Action
+}
+
+func (e *ForbiddenError) Error() string {
+	return "forbidden: " + string(e.Action)
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
@@ -0,0 +1,90 @@
+package domain
+
+import "time"
+
+// AuditAction names a privileged action recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditEditPost          AuditAction = "edit_post"
+	AuditDeletePost        AuditAction = "delete_post"
+	AuditHidePost          AuditAction = "hide_post"
+	AuditEditComment       AuditAction = "edit_comment"
+	AuditDeleteComment     AuditAction = "delete_comment"
+	AuditHideComment       AuditAction = "hide_comment"
+	AuditDismissReports    AuditAction = "dismiss_reports"
+	AuditWarnAuthor        AuditAction = "warn_author"
+	AuditChangeRole        AuditAction = "change_role"
+	AuditSuspend           AuditAction = "suspend"
+	AuditUnsuspend         AuditAction = "unsuspend"
+	AuditBan               AuditAction = "ban"
+	AuditUnban             AuditAction = "unban"
+	AuditShadowBan         AuditAction = "shadow_ban"
+	AuditUnshadowBan       AuditAction = "unshadow_ban"
+	AuditResetPassword     AuditAction = "reset_password"
+	AuditSignOut           AuditAction = "sign_out"
+	AuditResetTwoFactor    AuditAction = "reset_2fa"
+	AuditCreateCategory    AuditAction = "create_category"
+	AuditEditCategory      AuditAction = "edit_category"
+	AuditMoveCategory      AuditAction = "move_category"
+	AuditArchiveCategory   AuditAction = "archive_category"
+	AuditUnarchiveCategory AuditAction = "unarchive_category"
+	AuditMergeCategory     AuditAction = "merge_category"
+	AuditMergeTag          AuditAction = "merge_tag"
+	AuditBanTag            AuditAction = "ban_tag"
+	AuditUnbanTag          AuditAction = "unban_tag"
+)
+
+// AuditActions lists the actions in the order the audit log filter offers
+// them.
+var AuditActions = []AuditAction{
+	AuditEditPost, AuditDeletePost, AuditHidePost,
+	AuditEditComment, AuditDeleteComment, AuditHideComment,
+	AuditDismissReports, AuditWarnAuthor,
+	AuditChangeRole, AuditSuspend, AuditUnsuspend, AuditBan, AuditUnban, AuditShadowBan, AuditUnshadowBan,
+	AuditResetPassword, AuditSignOut, AuditResetTwoFactor,
+	AuditCreateCategory, AuditEditCategory, AuditMoveCategory, AuditArchiveCategory, AuditUnarchiveCategory, AuditMergeCategory,
+	AuditMergeTag, AuditBanTag, AuditUnbanTag,
+}
+
+// AuditTarget is the kind of thing an audited action was applied to.
+type AuditTarget string
+
+const (
+	TargetPost     AuditTarget = "post"
+	TargetComment  AuditTarget = "comment"
+	TargetUser     AuditTarget = "user"
+	TargetCategory AuditTarget = "category"
+	TargetTag      AuditTarget = "tag"
+)
+
+// AuditTargets lists the kinds of target in the order the filter offers them.
+var AuditTargets = []AuditTarget{TargetPost, TargetComment, TargetUser, TargetCategory, TargetTag}
+
+// AuditEntry records who did what to which post, comment, user, category or
+// tag. Before is a JSON snapshot of the target as it was before the action.
+// Reason is the moderator's note or the ban reason; role changes and
+// suspensions record their new value there. Entries made outside a
+// session, such as from the command line, have ActorId 0.
+type AuditEntry struct {
+	Id           int
+	ActorId      int
+	ActorName    string
+	Action       AuditAction
+	TargetType   AuditTarget
+	TargetId     int
+	Before       string
+	Reason       string
+	CreationDate time.Time
+}
+
+// AuditFilter selects audit log entries. Zero fields match everything.
+type AuditFilter struct {
+	ActorName  string
+	Action     AuditAction
+	TargetType AuditTarget
+	TargetId   int
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
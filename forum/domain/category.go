@@ -0,0 +1,49 @@
+package domain
+
+import "fmt"
+
+// Category is a topic posts are filed under; a post belongs to one or more.
+// Slug identifies the category in forms and URLs, and Position orders the
+// category list.
+//
+// Archived categories take no new posts. PostPolicy decides who may start
+// posts, AllowComments whether posts may be commented on, and a ReadOnly
+// category is an announcement board that only moderators write in.
+type Category struct {
+	Id            int
+	Slug          string
+	Name          string
+	Description   string
+	Position      int
+	Archived      bool
+	PostPolicy    PostPolicy
+	AllowComments bool
+	ReadOnly      bool
+}
+
+// PostPolicy is who may start posts in a category.
+type PostPolicy string
+
+const (
+	PostEveryone   PostPolicy = "everyone"
+	PostVerified   PostPolicy = "verified"
+	PostModerators PostPolicy = "moderators"
+)
+
+// PostPolicies lists the policies in the order they are offered to admins.
+var PostPolicies = []PostPolicy{PostEveryone, PostVerified, PostModerators}
+
+// CategoryRuleError refuses a post or comment the rules of a category do not
+// allow. It matches ErrForbidden with errors.Is.
+type CategoryRuleError struct {
+	Category string
+	Rule     string
+}
+
+func (e *CategoryRuleError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Category, e.Rule)
+}
+
+func (e *CategoryRuleError) Is(target error) bool {
+	return target == ErrForbidden
+}
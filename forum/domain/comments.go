@@ -3,12 +3,16 @@ package domain
 import "time"
 
 type Comments struct {
-	CommentId    int
-	PostId       int
-	UserId       int
-	Username     string
-	Content      string
+	CommentId int
+	PostId    int
+	UserId    int
+	Username  string
+	Content   string
+	// ContentHTML is Content rendered from Markdown, as for posts.
+	ContentHTML  string
 	Likes        int
 	Dislikes     int
+	Hidden       bool
+	Shadowed     bool
 	CreationDate time.Time
 }
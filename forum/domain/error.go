@@ -7,4 +7,48 @@ var (
 	ErrInvalidDataonRegistartion = errors.New("Invalid username, email or password")
 	ErrUserAlreadyExist          = errors.New("User already exist")
 	ErrSessionNotFound           = errors.New("session not found")
+	ErrSessionExpired            = errors.New("session expired")
+	ErrInvalidResetToken         = errors.New("invalid or expired reset link")
+	ErrInvalidPassword           = errors.New("password must be between 6 and 30 characters")
+	ErrInvalidVerifyToken        = errors.New("invalid or expired verification link")
+	ErrEmailNotVerified          = errors.New("email address is not verified")
+	ErrInvalidTwoFactorCode      = errors.New("invalid authentication code")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrInvalidLoginChallenge     = errors.New("login attempt expired, sign in again")
+	ErrForbidden                 = errors.New("forbidden")
+	ErrPasskeyNotFound           = errors.New("passkey not found")
+	ErrInvalidPasskey            = errors.New("passkey could not be verified")
+	ErrUnknownProvider           = errors.New("unknown sign-in provider")
+	ErrOAuthFailed               = errors.New("sign-in with the provider failed, try again")
+	ErrOAuthNoEmail              = errors.New("the provider did not share an email address")
+	ErrOAuthEmailTaken           = errors.New("an account with this email already exists, sign in with your password and connect the provider from your settings")
+	ErrIdentityTaken             = errors.New("this account is already connected to another user")
+	ErrIdentityNotFound          = errors.New("connected account not found")
+	ErrTooManyAttempts           = errors.New("too many attempts, try again later")
+	ErrPostNotFound              = errors.New("post not found")
+	ErrCommentNotFound           = errors.New("comment not found")
+	ErrInvalidReport             = errors.New("choose a reason for the report")
+	ErrAlreadyReported           = errors.New("you have already reported this")
+	ErrNoOpenReports             = errors.New("there are no open reports for this item")
+	ErrAccountBanned             = errors.New("this account has been banned")
+	ErrAccountSuspended          = errors.New("your account is suspended")
+	ErrSelfAction                = errors.New("you cannot do this to your own account")
+	ErrInvalidRole               = errors.New("unknown role")
+	ErrNoCategory                = errors.New("choose at least one category")
+	ErrInvalidCategory           = errors.New("unknown category")
+	ErrCategoryNotFound          = errors.New("category not found")
+	ErrCategoryExists            = errors.New("a category with this slug already exists")
+	ErrInvalidCategoryName       = errors.New("a category needs a name and a slug made of a-z, 0-9 and -")
+	ErrInvalidPostPolicy         = errors.New("unknown posting rule")
+	ErrMergeSameCategory         = errors.New("a category cannot be merged into itself")
+	ErrInvalidTag                = errors.New("tags are up to 30 letters and digits, with - between words")
+	ErrTooManyTags               = errors.New("a post can have at most 5 tags")
+	ErrTagNotFound               = errors.New("tag not found")
+	ErrMergeSameTag              = errors.New("a tag cannot be merged into itself")
+	ErrEmptySearch               = errors.New("type some words to search for")
+	ErrSearchTooLong             = errors.New("the search is too long")
+	ErrSearchUnavailable         = errors.New("search is not available on this server")
+	ErrInvalidSearch             = errors.New("the search could not be understood")
+	ErrSavedSearchNotFound       = errors.New("saved search not found")
+	ErrTooManySavedSearches      = errors.New("you can save at most 20 searches, delete one first")
 )
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// UserIdentity links a forum account to an account at an external identity
+// provider.
+type UserIdentity struct {
+	Id           int
+	UserId       int
+	Provider     string
+	Subject      string
+	Email        string
+	CreationDate time.Time
+}
+
+// IdentityProvider is a provider users can sign in with, as shown on the
+// login page.
+type IdentityProvider struct {
+	Name        string
+	DisplayName string
+	Icon        string
+}
+
+// OAuthState is a sign-in with an external provider in progress. UserId is
+// set when a signed in user is connecting another account.
+type OAuthState struct {
+	Id             int
+	StateHash      string
+	Provider       string
+	CodeVerifier   string
+	UserId         int
+	ExpirationDate time.Time
+}
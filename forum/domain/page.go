@@ -0,0 +1,49 @@
+package domain
+
+// DefaultPageSize is the number of items on a page of a list when the
+// request does not ask for another size; MaxPageSize is the most it may ask
+// for.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageRequest asks for one page of a list ordered newest, or highest key,
+// first. The page starts after the item Cursor points at or, with Backward,
+// ends just before it; a zero Cursor asks for the first page.
+type PageRequest struct {
+	Cursor   Cursor
+	Backward bool
+	Size     int
+}
+
+// Limit is the page size, falling back to DefaultPageSize for sizes out of
+// range.
+func (p PageRequest) Limit() int {
+	if p.Size < 1 || p.Size > MaxPageSize {
+		return DefaultPageSize
+	}
+	return p.Size
+}
+
+// Cursor points at an item of a list by its id and, for lists ordered by
+// something else such as a score, by its Key; the id then orders items with
+// the same key.
+type Cursor struct {
+	Id  int
+	Key float64
+}
+
+// IsZero reports whether the cursor points nowhere, that is at the start of
+// the list.
+func (c Cursor) IsZero() bool {
+	return c.Id == 0
+}
+
+// Page tells where the pages before and after a page of a list are: Prev
+// points at the first item of the page and Next at the last. A nil cursor
+// means there is no page on that side.
+type Page struct {
+	Prev *Cursor
+	Next *Cursor
+}
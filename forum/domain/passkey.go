@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Passkey is a WebAuthn credential a user has registered for passwordless
+// login.
+type Passkey struct {
+	Id           int
+	UserId       int
+	CredentialId []byte
+	PublicKey    []byte
+	SignCount    uint32
+	Name         string
+	CreationDate time.Time
+	LastUsed     time.Time
+}
+
+// WebAuthnChallenge is an open registration or login ceremony. UserId is zero
+// for logins, where the user is only known from the credential.
+type WebAuthnChallenge struct {
+	Id             int
+	UserId         int
+	ChallengeHash  string
+	Type           string
+	ExpirationDate time.Time
+}
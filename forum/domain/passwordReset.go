@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type PasswordReset struct {
+	Id             int
+	UserId         int
+	TokenHash      string
+	CreationDate   time.Time
+	ExpirationDate time.Time
+}
@@ -3,16 +3,33 @@ package domain
 import "time"
 
 type Posts struct {
-	PostId       int
-	UserId       int
-	Username     string
-	Category     string
-	Title        string
-	ImageField   string
-	Content      string
-	CategoryId   int
+	PostId     int
+	UserId     int
+	Username   string
+	Categories []Category
+	Tags       []string
+	Title      string
+	ImageField string
+	Content    string
+	// ContentHTML is Content rendered from Markdown and sanitized, ready to
+	// be put in a page as is.
+	ContentHTML  string
 	Likes        int
 	Dislikes     int
 	Comments     []Comments
+	Hidden       bool
+	Shadowed     bool
 	CreationDate time.Time
 }
+
+// PostFilter selects posts by category: those in any of Categories, given
+// as slugs, or in all of them when MatchAll is set. Tag, when set, selects
+// the posts carrying that tag instead. Sort orders the posts, and Period
+// limits SortTop to recent ones.
+type PostFilter struct {
+	Categories []string
+	MatchAll   bool
+	Tag        string
+	Sort       PostSort
+	Period     Period
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// ReportReason is why a user flagged a post or comment.
+type ReportReason string
+
+const (
+	ReasonSpam       ReportReason = "spam"
+	ReasonHarassment ReportReason = "harassment"
+	ReasonHate       ReportReason = "hate"
+	ReasonExplicit   ReportReason = "explicit"
+	ReasonOther      ReportReason = "other"
+)
+
+// ReportReasons lists the reasons in the order they are offered to users.
+var ReportReasons = []ReportReason{ReasonSpam, ReasonHarassment, ReasonHate, ReasonExplicit, ReasonOther}
+
+// ModerationAction is how a moderator resolves the reports on an item.
+type ModerationAction string
+
+const (
+	ModerationDismiss ModerationAction = "dismiss"
+	ModerationHide    ModerationAction = "hide"
+	ModerationDelete  ModerationAction = "delete"
+	ModerationWarn    ModerationAction = "warn"
+)
+
+// Report is a user's complaint about a post, or about a comment when
+// CommentId is set. Open reports have an empty Resolution.
+type Report struct {
+	Id           int
+	ReporterId   int
+	ReporterName string
+	PostId       int
+	CommentId    int
+	Reason       ReportReason
+	Details      string
+	Resolution   ModerationAction
+	ModeratorId  int
+	CreationDate time.Time
+	ResolvedDate time.Time
+}
+
+// ModerationItem is a reported post or comment together with its open
+// reports. Post or Comment is nil if the content was deleted meanwhile.
+type ModerationItem struct {
+	PostId    int
+	CommentId int
+	Post      *Posts
+	Comment   *Comments
+	Reports   []Report
+}
@@ -0,0 +1,24 @@
+package domain
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Roles lists the roles from least to most privileged.
+var Roles = []Role{RoleUser, RoleModerator, RoleAdmin}
+
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// AtLeast reports whether r grants everything other does. Unknown roles
+// grant nothing.
+func (r Role) AtLeast(other Role) bool {
+	return roleRank[r] > 0 && roleRank[r] >= roleRank[other]
+}
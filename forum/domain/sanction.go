@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// BannedError is returned when a banned user tries to sign in. It matches
+// ErrAccountBanned with errors.Is.
+type BannedError struct {
+	Reason string
+}
+
+func (e *BannedError) Error() string {
+	if e.Reason == "" {
+		return ErrAccountBanned.Error()
+	}
+	return ErrAccountBanned.Error() + ": " + e.Reason
+}
+
+func (e *BannedError) Is(target error) bool {
+	return target == ErrAccountBanned
+}
+
+// SuspendedError is returned when a suspended user tries to write. It
+// matches ErrAccountSuspended with errors.Is.
+type SuspendedError struct {
+	Until time.Time
+}
+
+func (e *SuspendedError) Error() string {
+	return ErrAccountSuspended.Error() + " until " + e.Until.Format("2006-01-02 15:04")
+}
+
+func (e *SuspendedError) Is(target error) bool {
+	return target == ErrAccountSuspended
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// MaxSavedSearches is how many searches a user may save.
+const MaxSavedSearches = 20
+
+// SavedSearch is a search query a user kept to be told about new posts
+// matching it: on the notifications page with Notify, and in a daily mail
+// with Digest. LastPostId is the newest post it has been matched against.
+type SavedSearch struct {
+	Id           int
+	UserId       int
+	Name         string
+	Query        string
+	Notify       bool
+	Digest       bool
+	LastPostId   int
+	CreationDate time.Time
+}
+
+// SearchAlert is a new post found by a saved search of its owner. Notify
+// shows it on the notifications page; Pending keeps it for the next digest.
+type SearchAlert struct {
+	Id           int
+	SearchId     int
+	SearchName   string
+	OwnerId      int
+	PostId       int
+	Title        string
+	Username     string
+	Notify       bool
+	Pending      bool
+	CreationDate time.Time
+}
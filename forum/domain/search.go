@@ -0,0 +1,93 @@
+package domain
+
+import "time"
+
+// MaxSearchLength is the longest search query accepted, in bytes.
+const MaxSearchLength = 200
+
+// SearchKind is what a search looks through.
+type SearchKind string
+
+const (
+	SearchPosts    SearchKind = "posts"
+	SearchComments SearchKind = "comments"
+)
+
+// SearchQuery asks for the posts, or comments, matching Text, written in
+// the search query language: words, "quoted phrases" and operators such as
+// author:alice or likes:>10.
+type SearchQuery struct {
+	Text string
+	Kind SearchKind
+}
+
+// SearchFilter is a parsed search query. A result contains every term and
+// meets every operator; the zero value of an operator does not limit the
+// results.
+//
+// When searching comments, Author, Before and After apply to the comments
+// and the rest of the operators to the posts commented on.
+type SearchFilter struct {
+	Terms      []SearchTerm
+	Author     string
+	Categories []string
+	Tags       []string
+	Before     time.Time
+	After      time.Time
+	Likes      []Comparison
+	HasImage   bool
+	Unanswered bool
+}
+
+// IsZero reports whether the filter has neither terms nor operators, and so
+// would match everything.
+func (f SearchFilter) IsZero() bool {
+	return len(f.Terms) == 0 && f.Author == "" && len(f.Categories) == 0 && len(f.Tags) == 0 &&
+		f.Before.IsZero() && f.After.IsZero() && len(f.Likes) == 0 && !f.HasImage && !f.Unanswered
+}
+
+// SearchTerm is a word a search looks for, or with Phrase words that must
+// follow each other. Prefix matches every word starting with Text.
+type SearchTerm struct {
+	Text   string
+	Phrase bool
+	Prefix bool
+}
+
+// Comparison compares a count, such as the likes of a post, with Value.
+type Comparison struct {
+	Op    CompareOp
+	Value int
+}
+
+// CompareOp is how a Comparison compares.
+type CompareOp string
+
+const (
+	OpEqual        CompareOp = "="
+	OpGreater      CompareOp = ">"
+	OpGreaterEqual CompareOp = ">="
+	OpLess         CompareOp = "<"
+	OpLessEqual    CompareOp = "<="
+)
+
+// HighlightStart and HighlightEnd enclose the words of a SearchResult
+// snippet that match the query. They are control characters so that they
+// cannot come from the text itself.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// SearchResult is a post or comment found by a search, best match first.
+// Snippet is the part of its text around the matches, with the matching
+// words between HighlightStart and HighlightEnd. CommentId is 0 for posts;
+// Title is the title of the post, or of the post commented on.
+type SearchResult struct {
+	PostId       int
+	CommentId    int
+	Title        string
+	Snippet      string
+	Username     string
+	CreationDate time.Time
+}
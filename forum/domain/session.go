@@ -3,9 +3,29 @@ package domain
 import "time"
 
 type Session struct {
+	Id             int
 	UserId         int
 	Username       string
+	EmailVerified  bool
+	Role           Role
+	SuspendedUntil time.Time
+	ShadowBanned   bool
 	SessionId      string
+	UserAgent      string
+	IP             string
 	CreationDate   time.Time
+	LastSeen       time.Time
 	ExpiritionDate time.Time
 }
+
+// Suspended reports whether the session's user is barred from writing for
+// now.
+func (s *Session) Suspended() bool {
+	return s.SuspendedUntil.After(time.Now())
+}
+
+// Client describes the device a request comes from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// PostSort is the order a list of posts is shown in.
+type PostSort string
+
+const (
+	SortNew           PostSort = "new"
+	SortHot           PostSort = "hot"
+	SortTop           PostSort = "top"
+	SortControversial PostSort = "controversial"
+	SortComments      PostSort = "comments"
+)
+
+// PostSorts lists the sort modes in the order they are offered.
+var PostSorts = []PostSort{SortNew, SortHot, SortTop, SortControversial, SortComments}
+
+// Valid reports whether s is one of PostSorts.
+func (s PostSort) Valid() bool {
+	for _, sort := range PostSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
+}
+
+// Period limits SortTop to the posts created within it.
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+	PeriodAll   Period = "all"
+)
+
+// Periods lists the periods in the order they are offered.
+var Periods = []Period{PeriodDay, PeriodWeek, PeriodMonth, PeriodAll}
+
+// Valid reports whether p is one of Periods.
+func (p Period) Valid() bool {
+	for _, period := range Periods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// Since returns when the period started as seen at now, or the zero time
+// for PeriodAll.
+func (p Period) Since(now time.Time) time.Time {
+	switch p {
+	case PeriodDay:
+		return now.AddDate(0, 0, -1)
+	case PeriodWeek:
+		return now.AddDate(0, 0, -7)
+	case PeriodMonth:
+		return now.AddDate(0, -1, 0)
+	}
+	return time.Time{}
+}
+
+// hotEpoch and hotHalfLife set the time decay of HotScore: a post needs ten
+// times the score of one hotHalfLife seconds older to rank above it.
+const (
+	hotEpoch    = 1577836800 // 2020-01-01 UTC
+	hotHalfLife = 45000
+)
+
+// HotScore ranks a post by its score, likes minus dislikes, on a log scale
+// plus its age, so that new posts rise above old ones unless those did much
+// better. It depends only on the votes and the creation date, so it only
+// changes when the votes do.
+func HotScore(likes, dislikes int, created time.Time) float64 {
+	score := float64(likes - dislikes)
+	order := math.Log10(math.Max(math.Abs(score), 1))
+	var sign float64
+	switch {
+	case score > 0:
+		sign = 1
+	case score < 0:
+		sign = -1
+	}
+	return sign*order + float64(created.Unix()-hotEpoch)/hotHalfLife
+}
+
+// Controversy ranks a post by how many votes it got and how evenly they
+// split between likes and dislikes; it is zero without both.
+func Controversy(likes, dislikes int) float64 {
+	if likes <= 0 || dislikes <= 0 {
+		return 0
+	}
+	l, d := float64(likes), float64(dislikes)
+	balance := math.Min(l, d) / math.Max(l, d)
+	return math.Pow(l+d, balance)
+}
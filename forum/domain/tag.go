@@ -0,0 +1,29 @@
+package domain
+
+import "fmt"
+
+// MaxTagsPerPost and MaxTagLength limit the tags a user adds to a post.
+const (
+	MaxTagsPerPost = 5
+	MaxTagLength   = 30
+)
+
+// Tag is a free-form label users add to posts, stored in its normalized
+// form: lower case letters and digits joined by single dashes. Banned tags
+// cannot be added to posts and are left out wherever tags are shown.
+// PostCount is the number of posts the viewer may see under the tag.
+type Tag struct {
+	Id        int
+	Name      string
+	Banned    bool
+	PostCount int
+}
+
+// BannedTagError refuses a post carrying a tag moderators have banned.
+type BannedTagError struct {
+	Tag string
+}
+
+func (e *BannedTagError) Error() string {
+	return fmt.Sprintf("the tag %q is not allowed", e.Tag)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Throttle counts recent events, such as failed logins, for one username or
+// IP address.
+type Throttle struct {
+	Key          string
+	Count        int
+	LastEvent    time.Time
+	BlockedUntil time.Time
+}
+
+// ThrottleError is returned while attempts are blocked. It matches
+// ErrTooManyAttempts with errors.Is.
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottleError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
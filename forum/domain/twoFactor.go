@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// TwoFactor is a user's TOTP enrollment. It is stored before the user has
+// confirmed a first code, with Enabled set to false.
+type TwoFactor struct {
+	UserId       int
+	Secret       string
+	Enabled      bool
+	LastCounter  int64
+	CreationDate time.Time
+}
+
+// TwoFactorStatus is what the settings page shows. While setup is pending,
+// SetupSecret and SetupURI hold the secret to enroll in an authenticator app.
+type TwoFactorStatus struct {
+	Enabled           bool
+	SetupSecret       string
+	SetupURI          string
+	RecoveryCodesLeft int
+}
+
+// LoginChallenge is a login waiting for its second factor.
+type LoginChallenge struct {
+	Id             int
+	UserId         int
+	TokenHash      string
+	Attempts       int
+	ExpirationDate time.Time
+}
@@ -5,7 +5,25 @@ import "time"
 type User struct {
 	Username         string
 	UserId           int
-	Password         string
+	Password         string `json:"-"`
 	Email            string
+	EmailVerified    bool
+	Role             Role
 	RegistrationDate time.Time
+	SuspendedUntil   time.Time
+	Banned           bool
+	BanReason        string
+	ShadowBanned     bool
+}
+
+// Suspended reports whether the user is barred from writing for now.
+func (u User) Suspended() bool {
+	return u.SuspendedUntil.After(time.Now())
+}
+
+// UserSummary is a user as listed on the admin dashboard.
+type UserSummary struct {
+	User
+	PostCount    int
+	CommentCount int
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+// HandleAdminCategories lists the categories with actions to create, edit,
+// reorder, archive and merge them.
+func (hh *HttpHandler) HandleAdminCategories(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err == nil {
+		err = hh.business.Authorize(session, domain.ActionManageCategories, 0)
+	}
+	if err != nil {
+		hh.Handle403(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		action := r.PostFormValue("action")
+		var id int
+		if action != "create" {
+			id, err = strconv.Atoi(r.PostFormValue("id"))
+			if err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+		}
+		category := domain.Category{
+			Id:            id,
+			Slug:          r.PostFormValue("slug"),
+			Name:          r.PostFormValue("name"),
+			Description:   r.PostFormValue("description"),
+			PostPolicy:    domain.PostPolicy(r.PostFormValue("post_policy")),
+			AllowComments: r.PostFormValue("allow_comments") != "",
+			ReadOnly:      r.PostFormValue("read_only") != "",
+		}
+
+		var message string
+		switch action {
+		case "create":
+			err = hh.business.CreateCategory(session, category)
+			message = fmt.Sprintf("%s has been created.", category.Name)
+		case "update":
+			err = hh.business.UpdateCategory(session, category)
+			message = fmt.Sprintf("%s has been saved.", category.Name)
+		case "archive":
+			err = hh.business.ArchiveCategory(session, id, true)
+			message = fmt.Sprintf("%s is archived.", category.Name)
+		case "unarchive":
+			err = hh.business.ArchiveCategory(session, id, false)
+			message = fmt.Sprintf("%s is open for new posts again.", category.Name)
+		case "up":
+			err = hh.business.MoveCategory(session, id, -1)
+		case "down":
+			err = hh.business.MoveCategory(session, id, 1)
+		case "merge":
+			into, convErr := strconv.Atoi(r.PostFormValue("into"))
+			if convErr != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			err = hh.business.MergeCategory(session, id, into)
+			message = fmt.Sprintf("%s has been merged.", category.Name)
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidCategoryName) || errors.Is(err, domain.ErrCategoryExists) ||
+				errors.Is(err, domain.ErrInvalidPostPolicy) || errors.Is(err, domain.ErrMergeSameCategory) ||
+				errors.Is(err, domain.ErrCategoryNotFound) {
+				hh.renderAdminCategories(w, r, session, "", err.Error())
+				return
+			}
+			hh.actionFailed(w, r, err, "category action")
+			return
+		}
+		hh.renderAdminCategories(w, r, session, message, "")
+	} else if r.Method == http.MethodGet {
+		hh.renderAdminCategories(w, r, session, "", "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) renderAdminCategories(w http.ResponseWriter, r *http.Request, session *domain.Session, message, errorMessage string) {
+	categories, err := hh.business.GetCategories()
+	if err != nil {
+		hh.actionFailed(w, r, err, "categories")
+		return
+	}
+	internal.RenderAdminCategoriesPage(w, r, session.Username, categories, message, errorMessage)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+const maxSuspensionDays = 3650
+
+// HandleAdmin is the admin dashboard: a searchable list of users with
+// actions to change their role, sanction them or reset their credentials.
+func (hh *HttpHandler) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err == nil {
+		err = hh.business.Authorize(session, domain.ActionManageUsers, 0)
+	}
+	if err != nil {
+		hh.Handle403(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		userID, err := strconv.Atoi(r.PostFormValue("user_id"))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		username := r.PostFormValue("username")
+		query := r.PostFormValue("q")
+
+		var message string
+		switch r.PostFormValue("action") {
+		case "role":
+			role := domain.Role(r.PostFormValue("role"))
+			err = hh.business.ChangeUserRole(session, userID, role)
+			message = fmt.Sprintf("%s is now %s.", username, role)
+		case "suspend":
+			days, convErr := strconv.Atoi(r.PostFormValue("days"))
+			if convErr != nil || days < 1 || days > maxSuspensionDays {
+				hh.renderAdmin(w, r, session, query, "", fmt.Sprintf("Suspensions last from 1 to %d days", maxSuspensionDays))
+				return
+			}
+			until := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+			err = hh.business.SuspendUser(session, userID, until)
+			message = fmt.Sprintf("%s is suspended until %s.", username, until.Format("2006-01-02 15:04"))
+		case "unsuspend":
+			err = hh.business.SuspendUser(session, userID, time.Time{})
+			message = fmt.Sprintf("The suspension of %s has been lifted.", username)
+		case "ban":
+			err = hh.business.BanUser(session, userID, r.PostFormValue("reason"))
+			message = fmt.Sprintf("%s has been banned.", username)
+		case "unban":
+			err = hh.business.UnbanUser(session, userID)
+			message = fmt.Sprintf("%s is no longer banned.", username)
+		case "shadow_ban":
+			err = hh.business.ShadowBanUser(session, userID, true)
+			message = fmt.Sprintf("%s is shadow banned.", username)
+		case "unshadow_ban":
+			err = hh.business.ShadowBanUser(session, userID, false)
+			message = fmt.Sprintf("The shadow ban of %s has been lifted.", username)
+		case "reset_password":
+			err = hh.business.ForcePasswordReset(session, userID)
+			message = fmt.Sprintf("The password of %s has been reset and a reset link sent to them.", username)
+		case "sign_out":
+			err = hh.business.SignOutUser(session, userID)
+			message = fmt.Sprintf("%s has been signed out everywhere.", username)
+		case "reset_2fa":
+			err = hh.business.ResetTwoFactor(session, username)
+			message = fmt.Sprintf("Two-factor authentication of %s has been reset.", username)
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrSelfAction) || errors.Is(err, domain.ErrInvalidRole) || errors.Is(err, domain.ErrInvalidUser) {
+				hh.renderAdmin(w, r, session, query, "", err.Error())
+				return
+			}
+			hh.actionFailed(w, r, err, "admin action")
+			return
+		}
+		hh.renderAdmin(w, r, session, query, message, "")
+	} else if r.Method == http.MethodGet {
+		hh.renderAdmin(w, r, session, r.URL.Query().Get("q"), "", "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) renderAdmin(w http.ResponseWriter, r *http.Request, session *domain.Session, query, message, errorMessage string) {
+	users, err := hh.business.SearchUsers(session, query)
+	if err != nil {
+		hh.actionFailed(w, r, err, "admin dashboard")
+		return
+	}
+	internal.RenderAdminPage(w, r, session.Username, query, users, message, errorMessage)
+}
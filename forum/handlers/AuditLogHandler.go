@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+const auditPageSize = 200
+
+// auditExportEntry is an audit log entry in the JSON export.
+type auditExportEntry struct {
+	Id         int                `json:"id"`
+	ActorId    int                `json:"actor_id"`
+	Actor      string             `json:"actor"`
+	Action     domain.AuditAction `json:"action"`
+	TargetType domain.AuditTarget `json:"target_type"`
+	TargetId   int                `json:"target_id"`
+	Before     json.RawMessage    `json:"before"`
+	Reason     string             `json:"reason"`
+	Date       time.Time          `json:"date"`
+}
+
+// HandleAuditLog lists the audit log filtered by the query parameters, or
+// exports every matching entry as JSON when format=json.
+func (hh *HttpHandler) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	session, err := hh.GetUsername(w, r)
+	if err == nil {
+		err = hh.business.Authorize(session, domain.ActionManageUsers, 0)
+	}
+	if err != nil {
+		hh.Handle403(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	export := query.Get("format") == "json"
+	filter, err := parseAuditFilter(query)
+	if err != nil {
+		if export {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		internal.RenderAuditLogPage(w, r, session.Username, query, nil, "", err.Error())
+		return
+	}
+	if !export {
+		filter.Limit = auditPageSize
+	}
+	entries, err := hh.business.GetAuditLog(session, filter)
+	if err != nil {
+		hh.actionFailed(w, r, err, "audit log")
+		return
+	}
+
+	if export {
+		exported := make([]auditExportEntry, 0, len(entries))
+		for _, e := range entries {
+			var before json.RawMessage
+			if e.Before != "" {
+				before = json.RawMessage(e.Before)
+			}
+			exported = append(exported, auditExportEntry{e.Id, e.ActorId, e.ActorName, e.Action, e.TargetType, e.TargetId, before, e.Reason, e.CreationDate})
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="audit-log.json"`)
+		writeJSON(w, http.StatusOK, exported)
+		return
+	}
+	exportQuery := url.Values{}
+	for key, values := range query {
+		exportQuery[key] = values
+	}
+	exportQuery.Set("format", "json")
+	internal.RenderAuditLogPage(w, r, session.Username, query, entries, "/admin/audit?"+exportQuery.Encode(), "")
+}
+
+// parseAuditFilter reads the audit log filter from the query. Dates are
+// whole days in server time, both ends included.
+func parseAuditFilter(query url.Values) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		ActorName:  strings.TrimSpace(query.Get("actor")),
+		Action:     domain.AuditAction(query.Get("action")),
+		TargetType: domain.AuditTarget(query.Get("target")),
+	}
+	var err error
+	if id := strings.TrimSpace(query.Get("target_id")); id != "" {
+		filter.TargetId, err = strconv.Atoi(id)
+		if err != nil {
+			return filter, errors.New("the target id must be a number")
+		}
+	}
+	if since := query.Get("since"); since != "" {
+		filter.Since, err = time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			return filter, errors.New("dates must be written as YYYY-MM-DD")
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		day, err := time.ParseInLocation("2006-01-02", until, time.Local)
+		if err != nil {
+			return filter, errors.New("dates must be written as YYYY-MM-DD")
+		}
+		filter.Until = day.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
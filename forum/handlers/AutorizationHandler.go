@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"forum/forum/domain"
 	"forum/forum/internal"
+	"forum/forum/middleware"
+
+	"github.com/gofrs/uuid"
 )
 
 func (hh *HttpHandler) HandleUserLogin(w http.ResponseWriter, r *http.Request) {
@@ -20,35 +26,155 @@ func (hh *HttpHandler) HandleUserLogin(w http.ResponseWriter, r *http.Request) {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		sessionId, err := hh.business.Login(username, password)
+		sessionId, challenge, err := hh.business.Login(username, password, clientInfo(r))
 		if err != nil {
+			if message, ok := throttled(w, err); ok {
+				internal.RenderLoginPage(w, r, message, hh.business.OAuthProviders())
+				return
+			}
 			if errors.Is(err, domain.ErrInvalidUser) {
-				internal.RenderLoginPage(w, r, "Invalid username or password")
+				internal.RenderLoginPage(w, r, "Invalid username or password", hh.business.OAuthProviders())
+				return
+			}
+			if message, ok := bannedMessage(err); ok {
+				w.WriteHeader(http.StatusForbidden)
+				internal.RenderLoginPage(w, r, message, hh.business.OAuthProviders())
 				return
 			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if challenge != "" {
+			setLoginChallenge(w, r, challenge)
+			return
+		}
 
-		expiry := time.Now().Add(24 * time.Hour)
+		hh.startSession(w, r, sessionId)
+	} else if r.Method == http.MethodGet {
+		internal.RenderLoginPage(w, r, "", hh.business.OAuthProviders())
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+// HandleTwoFactorLogin is the second login step for users with two-factor
+// authentication enabled.
+func (hh *HttpHandler) HandleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	challengeCookie, err := r.Cookie("login_challenge")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method == http.MethodPost {
+		code := r.FormValue("code")
 
-		cookie := http.Cookie{
-			Name:     "session_id",
-			Value:    sessionId.String(),
-			Expires:  expiry,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
+		sessionId, err := hh.business.CompleteTwoFactorLogin(challengeCookie.Value, code, clientInfo(r))
+		if err != nil {
+			if message, ok := throttled(w, err); ok {
+				internal.RenderTwoFactorLoginPage(w, r, message)
+				return
+			}
+			if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+				internal.RenderTwoFactorLoginPage(w, r, "Invalid authentication code")
+				return
+			}
+			if errors.Is(err, domain.ErrInvalidLoginChallenge) {
+				clearLoginChallenge(w)
+				internal.RenderLoginPage(w, r, "Login attempt expired, sign in again", hh.business.OAuthProviders())
+				return
+			}
+			if message, ok := bannedMessage(err); ok {
+				clearLoginChallenge(w)
+				w.WriteHeader(http.StatusForbidden)
+				internal.RenderLoginPage(w, r, message, hh.business.OAuthProviders())
+				return
+			}
+			log.Printf("Error with two-factor login:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
 		}
-		http.SetCookie(w, &cookie)
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		clearLoginChallenge(w)
+		hh.startSession(w, r, sessionId)
 	} else if r.Method == http.MethodGet {
-		internal.RenderLoginPage(w, r, "")
+		internal.RenderTwoFactorLoginPage(w, r, "")
 	} else {
 		w.WriteHeader(405)
 	}
 }
 
+func (hh *HttpHandler) startSession(w http.ResponseWriter, r *http.Request, sessionId uuid.UUID) {
+	session, err := hh.business.Session(sessionId.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	setSessionCookie(w, session.SessionId, session.ExpiritionDate)
+	middleware.ResetCSRF(w)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// throttled reports whether err means the client has to wait. If so it sets
+// the response status and returns a message saying for how long.
+func throttled(w http.ResponseWriter, err error) (string, bool) {
+	var throttleErr *domain.ThrottleError
+	if !errors.As(err, &throttleErr) {
+		return "", false
+	}
+	seconds := int(math.Ceil(throttleErr.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	wait := fmt.Sprintf("%d seconds", seconds)
+	if seconds == 1 {
+		wait = "1 second"
+	} else if seconds > 90 {
+		wait = fmt.Sprintf("%d minutes", (seconds+59)/60)
+	}
+	return "Too many attempts. Try again in " + wait + ".", true
+}
+
+// bannedMessage reports whether err turns away a banned user and returns the
+// message telling them why.
+func bannedMessage(err error) (string, bool) {
+	var banned *domain.BannedError
+	if !errors.As(err, &banned) {
+		return "", false
+	}
+	if banned.Reason == "" {
+		return "This account has been banned.", true
+	}
+	return "This account has been banned: " + banned.Reason, true
+}
+
+// setLoginChallenge sends a user who passed the first login step on to the
+// two-factor prompt.
+func setLoginChallenge(w http.ResponseWriter, r *http.Request, challenge string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "login_challenge",
+		Value:    challenge,
+		Path:     "/login",
+		MaxAge:   int((5 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+}
+
+func clearLoginChallenge(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "login_challenge",
+		Value:    "",
+		Path:     "/login",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 func (hh *HttpHandler) HandleUserRegistration(w http.ResponseWriter, r *http.Request) {
 	_, err := hh.GetUsername(w, r)
 	if err == nil {
@@ -60,38 +186,84 @@ func (hh *HttpHandler) HandleUserRegistration(w http.ResponseWriter, r *http.Req
 		email := strings.TrimSpace(r.FormValue("email"))
 		password := r.FormValue("password")
 
-		err := hh.business.Registration(username, password, email)
+		err := hh.business.Registration(username, password, email, clientInfo(r))
 		if err != nil {
+			if message, ok := throttled(w, err); ok {
+				internal.RenderRegisterPage(w, r, message, hh.business.OAuthProviders())
+				return
+			}
 			if errors.Is(err, domain.ErrInvalidDataonRegistartion) {
-				internal.RenderRegisterPage(w, r, "Invalid username or password")
+				internal.RenderRegisterPage(w, r, "Invalid username or password", hh.business.OAuthProviders())
 				return
 			}
 			if err == domain.ErrUserAlreadyExist {
-				internal.RenderRegisterPage(w, r, "This email or username already exist")
+				internal.RenderRegisterPage(w, r, "This email or username already exist", hh.business.OAuthProviders())
 				return
 			}
 			log.Printf("Error with registration:%s", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		http.Redirect(w, r, "/verify?sent=1", http.StatusSeeOther)
 	} else if r.Method == http.MethodGet {
-		internal.RenderRegisterPage(w, r, "")
+		internal.RenderRegisterPage(w, r, "", hh.business.OAuthProviders())
 	} else {
 		w.WriteHeader(405)
 	}
 }
 
+// HandleLogout only accepts POST so that a third-party page cannot sign users
+// out by embedding the URL in an image or link.
 func (hh *HttpHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(405)
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err == nil {
+		if r.PostFormValue("everywhere") != "" {
+			err = hh.business.LogoutEverywhere(sessionCookie.Value)
+		} else {
+			err = hh.business.Logout(sessionCookie.Value)
+		}
+		if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+			log.Printf("Error with logout:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
 	ClearSession(w)
+	middleware.ResetCSRF(w)
 
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
+// setSessionCookie uses SameSite=Lax: the cookie still comes along when
+// following a link to the forum, but not on cross-site form posts.
+func setSessionCookie(w http.ResponseWriter, sessionID string, expiry time.Time) {
+	cookie := http.Cookie{
+		Name:     "session_id",
+		Value:    sessionID,
+		Expires:  expiry,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, &cookie)
+}
+
 func ClearSession(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
-		Name:    "session_id",
-		Value:   "",
-		Expires: time.Now().Add(-1 * time.Hour),
+		Name:     "session_id",
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Now().Add(-1 * time.Hour),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
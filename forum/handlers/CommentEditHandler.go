@@ -39,10 +39,9 @@ func (hh *HttpHandler) HandleCommentEdit(w http.ResponseWriter, r *http.Request)
 			Content:   commentText,
 		}
 
-		err = hh.business.EditComment(commentID, newComment)
+		err = hh.business.EditComment(session, commentID, newComment)
 		if err != nil {
-			fmt.Print(err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			hh.actionFailed(w, r, err, "edit comment")
 			return
 		}
 		http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -54,14 +53,27 @@ func (hh *HttpHandler) HandleCommentEdit(w http.ResponseWriter, r *http.Request)
 			hh.Handle404(w, r)
 			return
 		}
-		posts, err := hh.business.GetAllPosts()
+		posts, page, err := hh.business.GetAllPosts(username, domain.PostFilter{}, internal.PageRequest(r, ""))
 		if err != nil {
 			fmt.Println("Cant get Posts")
 			return
 		}
 		if username.Username == "" {
 
-			internal.RenderMainPage(w, r, username, posts)
+			hh.renderMainPage(w, r, username, posts, page, domain.PostFilter{})
+			return
+		}
+		commentID, err := strconv.Atoi(commentIDStr)
+		if err != nil {
+			hh.Handle404(w, r)
+			return
+		}
+		comment, err := hh.business.GetCommentByID(commentID)
+		if err == nil {
+			err = hh.business.Authorize(username, domain.ActionEditComment, comment.UserId)
+		}
+		if err != nil {
+			hh.actionFailed(w, r, err, "edit comment")
 			return
 		}
 
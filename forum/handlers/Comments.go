@@ -17,6 +17,9 @@ func (hh *HttpHandler) HandleAddComment(w http.ResponseWriter, r *http.Request)
 			http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
 			return
 		}
+		if !hh.checkPermission(w, r, username, domain.ActionComment) {
+			return
+		}
 
 		err = r.ParseForm()
 		if err != nil {
@@ -26,39 +29,35 @@ func (hh *HttpHandler) HandleAddComment(w http.ResponseWriter, r *http.Request)
 
 		postIDStr := r.Form.Get("post_id")
 		commentText := r.Form.Get("comment_text")
-		userIdStr := r.Form.Get("user_id")
 		postID, err := strconv.Atoi(postIDStr)
 		if err != nil {
 
 			http.Error(w, "Invalid post ID", http.StatusBadRequest)
 			return
 		}
-		userId, err := strconv.Atoi(userIdStr)
-		if err != nil {
-
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
-			return
-		}
 		if len(strings.TrimSpace((commentText))) <= 0 {
 			fmt.Fprintf(w, "Comments text is required")
 			return
 		}
 		comment := domain.Comments{
 			PostId:       postID,
-			UserId:       userId,
-			Username:     username.Username,
 			Content:      commentText,
 			CreationDate: time.Now(),
 		}
 
-		err = hh.business.AddComment(comment, userId, postID)
+		err = hh.business.AddComment(username, comment)
 		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			hh.actionFailed(w, r, err, "add comment")
 			return
 		}
 
 		http.Redirect(w, r, "/post/?id="+postIDStr, http.StatusSeeOther)
 	} else if r.Method == http.MethodDelete {
+		session, err := hh.GetUsername(w, r)
+		if err != nil {
+			http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+			return
+		}
 		postID := r.URL.Query().Get("id")
 		if postID == "" {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
@@ -70,9 +69,9 @@ func (hh *HttpHandler) HandleAddComment(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		err = hh.business.DeleteComment(id)
+		err = hh.business.DeleteComment(session, id)
 		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			hh.actionFailed(w, r, err, "delete comment")
 			return
 		}
 		http.Redirect(w, r, "/history", http.StatusSeeOther)
@@ -84,6 +83,11 @@ func (hh *HttpHandler) HandleAddComment(w http.ResponseWriter, r *http.Request)
 
 func (hh *HttpHandler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
+		session, err := hh.GetUsername(w, r)
+		if err != nil {
+			http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+			return
+		}
 		commentID := r.PostFormValue("comment_id")
 		if commentID == "" {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
@@ -96,12 +100,13 @@ func (hh *HttpHandler) DeleteCommentHandler(w http.ResponseWriter, r *http.Reque
 			return
 		}
 
-		err = hh.business.DeleteComment(id)
+		err = hh.business.DeleteComment(session, id)
 		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			hh.actionFailed(w, r, err, "delete comment")
 			return
 		}
 		http.Redirect(w, r, "/history", http.StatusSeeOther)
 		return
 	}
+	w.WriteHeader(405)
 }
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
+	"forum/forum/domain"
 	"forum/forum/internal"
 )
 
@@ -13,5 +16,31 @@ func (hh *HttpHandler) Handle404(w http.ResponseWriter, r *http.Request) {
 
 func (hh *HttpHandler) Handle403(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(403)
-	internal.RenderError403Page(w, r)
+	internal.RenderError403Page(w, r, "")
+}
+
+// actionFailed answers a request whose Business call returned err. Refused
+// actions get the 403 page, which tells suspended users for how long and
+// explains category rules, missing posts, comments and tags the 404 page,
+// and users who have to verify their email first are sent to do so.
+func (hh *HttpHandler) actionFailed(w http.ResponseWriter, r *http.Request, err error, context string) {
+	var suspended *domain.SuspendedError
+	var rule *domain.CategoryRuleError
+	switch {
+	case errors.As(err, &suspended):
+		w.WriteHeader(403)
+		internal.RenderError403Page(w, r, "Your account is suspended until "+suspended.Until.Format("2006-01-02 15:04")+". You can still read the forum.")
+	case errors.As(err, &rule):
+		w.WriteHeader(403)
+		internal.RenderError403Page(w, r, rule.Error())
+	case errors.Is(err, domain.ErrForbidden):
+		hh.Handle403(w, r)
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		http.Redirect(w, r, "/verify", http.StatusSeeOther)
+	case errors.Is(err, domain.ErrPostNotFound), errors.Is(err, domain.ErrCommentNotFound), errors.Is(err, domain.ErrTagNotFound):
+		hh.Handle404(w, r)
+	default:
+		log.Printf("Error with %s:%s", context, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
 }
@@ -7,43 +7,37 @@ import (
 	"forum/forum/internal"
 )
 
+// HandleFilteredPosts lists the posts in the categories named by the category
+// parameters: in any of them, or in all of them with match=all, in the order
+// of the sort parameter.
 func (hh *HttpHandler) HandleFilteredPosts(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
-		categories := r.URL.Query()["category"]
+		query := r.URL.Query()
+		filter := domain.PostFilter{MatchAll: query.Get("match") == "all"}
+		filter.Sort, filter.Period = internal.PostSort(r)
+		for _, slug := range query["category"] {
+			if slug != "" && slug != "none" {
+				filter.Categories = append(filter.Categories, slug)
+			}
+		}
 
 		var posts []domain.Posts
+		var page domain.Page
 		var err error
+		username, _ := hh.GetUsername(w, r)
+		req := internal.PageRequest(r, "")
 
-		if len(categories) == 0 || (len(categories) == 1 && categories[0] == "none") {
-			posts, err = hh.business.GetAllPosts()
-			if err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
+		if len(filter.Categories) == 0 {
+			posts, page, err = hh.business.GetAllPosts(username, filter, req)
 		} else {
-			posts, err = hh.business.GetPostsByCategories(categories)
-			if err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
+			posts, page, err = hh.business.GetPostsByCategories(username, filter, req)
 		}
-
 		if err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
-		username, err := hh.GetUsername(w, r)
-		if err != nil {
-			internal.RenderMainPage(w, r, username, posts)
-			return
-		}
-		if username.Username == "" {
-			internal.RenderMainPage(w, r, username, posts)
-			return
-		}
-
-		internal.RenderMainPage(w, r, username, posts)
+		hh.renderMainPage(w, r, username, posts, page, filter)
 	} else {
 		w.WriteHeader(405)
 	}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -37,7 +38,15 @@ func (hh *HttpHandler) HandleEditPostDetails(w http.ResponseWriter, r *http.Requ
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		category := r.FormValue("category")
+		// Checked before the upload is saved; EditPost checks again.
+		post, err := hh.business.GetPostByID(postID)
+		if err == nil {
+			err = hh.business.Authorize(session, domain.ActionEditPost, post.UserId)
+		}
+		if err != nil {
+			hh.actionFailed(w, r, err, "edit post")
+			return
+		}
 		title := r.FormValue("title")
 		content := r.FormValue("content")
 
@@ -74,7 +83,7 @@ func (hh *HttpHandler) HandleEditPostDetails(w http.ResponseWriter, r *http.Requ
 
 			const maxFileSize = 20 << 20
 			if fileSize > maxFileSize {
-				internal.RenderEditPostPage(w, r, session.Username, "Image file size exceeds the limit (20MB) ", postIDStr)
+				hh.renderEditPostPage(w, r, session.Username, "Image file size exceeds the limit (20MB) ", postIDStr, formCategories(r), formTags(r))
 				return
 			}
 
@@ -102,25 +111,28 @@ func (hh *HttpHandler) HandleEditPostDetails(w http.ResponseWriter, r *http.Requ
 				return
 			}
 		}
-		if len(strings.TrimSpace(category)) <= 0 || len(strings.TrimSpace(title)) <= 0 {
-			internal.RenderEditPostPage(w, r, session.Username, "The post title and content must not be empty", postIDStr)
+		if len(strings.TrimSpace(title)) <= 0 {
+			hh.renderEditPostPage(w, r, session.Username, "The post title and content must not be empty", postIDStr, formCategories(r), formTags(r))
 			return
 		}
 		newPost := domain.Posts{
 			Username:     session.Username,
 			UserId:       session.UserId,
-			Category:     category,
+			Categories:   formCategories(r),
+			Tags:         formTags(r),
 			Title:        title,
 			Content:      content,
-			CategoryId:   1,
 			ImageField:   imagePathHTML,
 			CreationDate: time.Now(),
 		}
 
-		err = hh.business.EditPost(postID, newPost)
+		err = hh.business.EditPost(session, postID, newPost)
 		if err != nil {
-			fmt.Print(err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			if postFormError(err) {
+				hh.renderEditPostPage(w, r, session.Username, err.Error(), postIDStr, newPost.Categories, newPost.Tags)
+				return
+			}
+			hh.actionFailed(w, r, err, "edit post")
 			return
 		}
 		http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -132,22 +144,45 @@ func (hh *HttpHandler) HandleEditPostDetails(w http.ResponseWriter, r *http.Requ
 			hh.Handle404(w, r)
 			return
 		}
-		posts, err := hh.business.GetAllPosts()
+		posts, page, err := hh.business.GetAllPosts(username, domain.PostFilter{}, internal.PageRequest(r, ""))
 		if err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 		if username.Username == "" {
 
-			internal.RenderMainPage(w, r, username, posts)
+			hh.renderMainPage(w, r, username, posts, page, domain.PostFilter{})
 			return
 		}
-
-		err = internal.RenderEditPostPage(w, r, username.Username, "", postIDStr)
+		postID, err := strconv.Atoi(postIDStr)
 		if err != nil {
-			fmt.Println(err)
+			hh.Handle404(w, r)
+			return
+		}
+		post, err := hh.business.GetPostByID(postID)
+		if err == nil {
+			err = hh.business.Authorize(username, domain.ActionEditPost, post.UserId)
+		}
+		if err != nil {
+			hh.actionFailed(w, r, err, "edit post")
+			return
 		}
+
+		hh.renderEditPostPage(w, r, username.Username, "", postIDStr, post.Categories, post.Tags)
 	} else {
 		w.WriteHeader(405)
 	}
 }
+
+func (hh *HttpHandler) renderEditPostPage(w http.ResponseWriter, r *http.Request, username, errorMessage, postID string, chosen []domain.Category, tags []string) {
+	categories, err := hh.business.GetCategories()
+	if err != nil {
+		log.Printf("Error getting categories:%s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = internal.RenderEditPostPage(w, r, username, errorMessage, postID, categories, chosen, tags)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
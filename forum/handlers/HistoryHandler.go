@@ -24,7 +24,7 @@ func (hh *HttpHandler) UserActivityHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Retrieve the user's created posts and comments
-	userActivity, err := hh.business.GetUserActivity(username.UserId)
+	userActivity, page, err := hh.business.GetUserActivity(username.UserId, internal.PageRequest(r, ""))
 	if err != nil {
 		// Handle the error, e.g., by displaying an error page
 		fmt.Println(err)
@@ -33,5 +33,5 @@ func (hh *HttpHandler) UserActivityHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Render the user activity page
-	internal.RenderUserActivityPage(w, r, username.Username, userActivity)
+	internal.RenderUserActivityPage(w, r, username.Username, userActivity, page)
 }
@@ -23,14 +23,14 @@ func (hh *HttpHandler) HandleLikedPosts(w http.ResponseWriter, r *http.Request)
 			http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
 			return
 		}
-		likedPosts, err := hh.business.GetLikedPosts(session.UserId)
+		likedPosts, page, err := hh.business.GetLikedPosts(session.UserId, internal.PageRequest(r, ""))
 		if err != nil {
 			fmt.Println(err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
-		internal.RenderLikePages(w, r, session.Username, likedPosts)
+		internal.RenderLikePages(w, r, session.Username, likedPosts, page)
 	} else {
 		w.WriteHeader(405)
 	}
@@ -53,13 +53,13 @@ func (hh *HttpHandler) HandleDislikedPosts(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		dislikedPosts, err := hh.business.GetDislikedPosts(session.UserId)
+		dislikedPosts, page, err := hh.business.GetDislikedPosts(session.UserId, internal.PageRequest(r, ""))
 		if err != nil {
 			fmt.Println(err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
-		internal.RenderDislikePages(w, r, session.Username, dislikedPosts)
+		internal.RenderDislikePages(w, r, session.Username, dislikedPosts, page)
 	} else {
 		w.WriteHeader(405)
 	}
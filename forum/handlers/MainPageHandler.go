@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
 	"forum/forum/domain"
@@ -12,25 +12,28 @@ import (
 func (hh *HttpHandler) MainHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 
-		posts, err := hh.business.GetAllPosts()
+		// Guests get a nil session and see the posts everyone may see.
+		username, _ := hh.GetUsername(w, r)
+		var filter domain.PostFilter
+		filter.Sort, filter.Period = internal.PostSort(r)
+		posts, page, err := hh.business.GetAllPosts(username, filter, internal.PageRequest(r, ""))
 		if err != nil {
 			fmt.Println("Cant get Posts")
 			return
 		}
-		username, err := hh.GetUsername(w, r)
-		if err != nil {
-			if errors.Is(err, domain.ErrSessionNotFound) {
-				internal.RenderMainPage(w, r, username, posts)
-				return
-			}
-			internal.RenderMainPage(w, r, username, posts)
-			return
-		}
-		if username.Username == "" {
-			internal.RenderMainPage(w, r, username, posts)
-			return
-		}
 
-		internal.RenderMainPage(w, r, username, posts)
+		hh.renderMainPage(w, r, username, posts, page, filter)
+	}
+}
+
+// renderMainPage shows a page of posts on the index page together with the
+// category filter that selected them.
+func (hh *HttpHandler) renderMainPage(w http.ResponseWriter, r *http.Request, session *domain.Session, posts []domain.Posts, page domain.Page, filter domain.PostFilter) {
+	categories, err := hh.business.GetCategories()
+	if err != nil {
+		log.Printf("Error getting categories:%s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
+	internal.RenderMainPage(w, r, session, posts, page, categories, filter)
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+// HandleReport shows the report form for the post or comment named in the
+// query and files the report.
+func (hh *HttpHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+		return
+	}
+	postID, _ := strconv.Atoi(r.FormValue("post_id"))
+	commentID, _ := strconv.Atoi(r.FormValue("comment_id"))
+
+	var post domain.Posts
+	var comment *domain.Comments
+	if commentID != 0 {
+		c, err := hh.business.GetCommentByID(commentID)
+		if err != nil {
+			hh.actionFailed(w, r, err, "report")
+			return
+		}
+		comment = &c
+		postID = c.PostId
+	}
+	post, err = hh.business.GetPostByID(postID)
+	if err != nil {
+		hh.actionFailed(w, r, err, "report")
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		reason := domain.ReportReason(r.PostFormValue("reason"))
+		err = hh.business.Report(session, postID, commentID, reason, r.PostFormValue("details"))
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidReport) || errors.Is(err, domain.ErrAlreadyReported) {
+				internal.RenderReportPage(w, r, session.Username, post, comment, "", err.Error())
+				return
+			}
+			hh.actionFailed(w, r, err, "report")
+			return
+		}
+		internal.RenderReportPage(w, r, session.Username, post, comment, "Thank you. The moderators will look into your report.", "")
+	} else if r.Method == http.MethodGet {
+		internal.RenderReportPage(w, r, session.Username, post, comment, "", "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+// HandleModeration is the moderators' queue of reported content.
+func (hh *HttpHandler) HandleModeration(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		hh.Handle403(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		postID, err := strconv.Atoi(r.PostFormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		commentID, _ := strconv.Atoi(r.PostFormValue("comment_id"))
+		action := domain.ModerationAction(r.PostFormValue("action"))
+
+		err = hh.business.Moderate(session, postID, commentID, action, r.PostFormValue("note"))
+		if err != nil {
+			if errors.Is(err, domain.ErrNoOpenReports) || errors.Is(err, domain.ErrPostNotFound) || errors.Is(err, domain.ErrCommentNotFound) {
+				hh.renderModeration(w, r, session, err.Error())
+				return
+			}
+			hh.actionFailed(w, r, err, "moderation")
+			return
+		}
+		http.Redirect(w, r, "/moderation", http.StatusSeeOther)
+	} else if r.Method == http.MethodGet {
+		hh.renderModeration(w, r, session, "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) renderModeration(w http.ResponseWriter, r *http.Request, session *domain.Session, errorMessage string) {
+	items, err := hh.business.GetModerationQueue(session)
+	if err != nil {
+		hh.actionFailed(w, r, err, "moderation")
+		return
+	}
+	internal.RenderModerationPage(w, r, session.Username, items, errorMessage)
+}
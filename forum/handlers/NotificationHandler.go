@@ -17,16 +17,22 @@ func (hh *HttpHandler) NotificationHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	notifications, err := hh.business.GetAllNotifications(username.UserId)
+	notifications, page, err := hh.business.GetAllNotifications(username.UserId, internal.PageRequest(r, ""))
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	notifications_comments, err := hh.business.GetAllNotificationsComment(username.UserId)
+	notifications_comments, commentsPage, err := hh.business.GetAllNotificationsComment(username.UserId, internal.PageRequest(r, "comments_"))
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	internal.RenderNotifications(w, username.Username, notifications, notifications_comments) // Replace "Username" with the actual username
+	alerts, alertsPage, err := hh.business.GetSearchAlerts(username.UserId, internal.PageRequest(r, "alerts_"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	internal.RenderNotifications(w, r, username.Username, notifications, page, notifications_comments, commentsPage, alerts, alertsPage) // Replace "Username" with the actual username
 }
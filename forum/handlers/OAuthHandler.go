@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+
+	"github.com/gofrs/uuid"
+)
+
+// HandleOAuth serves /auth/<provider>, which sends the user to the provider,
+// and /auth/<provider>/callback, where the provider sends them back. A POST
+// to /auth/<provider> from the accounts page connects the provider to the
+// signed in user instead of signing in.
+func (hh *HttpHandler) HandleOAuth(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/auth/")
+	provider, callback := strings.CutSuffix(path, "/callback")
+	if provider == "" || strings.Contains(provider, "/") {
+		hh.Handle404(w, r)
+		return
+	}
+
+	if callback {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(405)
+			return
+		}
+		hh.handleOAuthCallback(w, r, provider)
+		return
+	}
+
+	linkUserID := 0
+	if r.Method == http.MethodPost {
+		session, err := hh.GetUsername(w, r)
+		if err != nil {
+			http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+			return
+		}
+		linkUserID = session.UserId
+	} else if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	authURL, state, err := hh.business.BeginOAuth(provider, linkUserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnknownProvider) {
+			hh.Handle404(w, r)
+			return
+		}
+		log.Printf("Error with %s sign-in:%s", provider, err)
+		internal.RenderLoginPage(w, r, domain.ErrOAuthFailed.Error(), hh.business.OAuthProviders())
+		return
+	}
+
+	// The state is also kept in a cookie so that a callback only completes
+	// in the browser that started the sign-in.
+	http.SetCookie(w, &http.Cookie{
+		Name:     "oauth_state",
+		Value:    state,
+		Path:     "/auth/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+func (hh *HttpHandler) handleOAuthCallback(w http.ResponseWriter, r *http.Request, provider string) {
+	state := r.URL.Query().Get("state")
+	stateCookie, err := r.Cookie("oauth_state")
+	http.SetCookie(w, &http.Cookie{
+		Name:     "oauth_state",
+		Value:    "",
+		Path:     "/auth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	session, sessionErr := hh.GetUsername(w, r)
+	if sessionErr != nil {
+		session = nil
+	}
+
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(stateCookie.Value)) != 1 {
+		hh.oauthFailed(w, r, session, domain.ErrOAuthFailed)
+		return
+	}
+	if r.URL.Query().Get("error") != "" {
+		// The user cancelled at the provider.
+		if session != nil {
+			http.Redirect(w, r, "/settings/accounts", http.StatusSeeOther)
+		} else {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		}
+		return
+	}
+
+	sessionId, challenge, err := hh.business.FinishOAuth(provider, state, r.URL.Query().Get("code"), clientInfo(r), session)
+	if err != nil {
+		hh.oauthFailed(w, r, session, err)
+		return
+	}
+	if challenge != "" {
+		setLoginChallenge(w, r, challenge)
+		return
+	}
+	if sessionId == uuid.Nil {
+		http.Redirect(w, r, "/settings/accounts", http.StatusSeeOther)
+		return
+	}
+	hh.startSession(w, r, sessionId)
+}
+
+func (hh *HttpHandler) oauthFailed(w http.ResponseWriter, r *http.Request, session *domain.Session, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownProvider):
+		hh.Handle404(w, r)
+		return
+	case errors.Is(err, domain.ErrOAuthFailed),
+		errors.Is(err, domain.ErrOAuthNoEmail),
+		errors.Is(err, domain.ErrOAuthEmailTaken),
+		errors.Is(err, domain.ErrIdentityTaken),
+		errors.Is(err, domain.ErrAccountBanned):
+	default:
+		log.Printf("Error with %s sign-in:%s", r.URL.Path, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if session != nil {
+		identities, listErr := hh.business.GetUserIdentities(session.UserId)
+		if listErr != nil {
+			log.Printf("Error with connected accounts:%s", listErr)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderAccountsPage(w, r, session.Username, identities, hh.business.OAuthProviders(), err.Error())
+		return
+	}
+	internal.RenderLoginPage(w, r, err.Error(), hh.business.OAuthProviders())
+}
+
+// HandleAccounts lists the external accounts connected to the user.
+func (hh *HttpHandler) HandleAccounts(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if r.PostFormValue("action") != "unlink" {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.Atoi(r.PostFormValue("id"))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		err = hh.business.UnlinkIdentity(session.UserId, id)
+		if err != nil && !errors.Is(err, domain.ErrIdentityNotFound) {
+			log.Printf("Error with connected accounts:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/settings/accounts", http.StatusSeeOther)
+	} else if r.Method == http.MethodGet {
+		identities, err := hh.business.GetUserIdentities(session.UserId)
+		if err != nil {
+			log.Printf("Error with connected accounts:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderAccountsPage(w, r, session.Username, identities, hh.business.OAuthProviders(), "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+	"forum/forum/middleware"
+	"forum/forum/webauthn"
+)
+
+// The /webauthn/ endpoints talk JSON to static/webauthn.js, which passes the
+// options to the browser's credential API and posts the result back.
+
+const maxWebAuthnBody = 64 << 10
+
+func (hh *HttpHandler) HandlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Sign in first")
+		return
+	}
+
+	options, err := hh.business.BeginPasskeyRegistration(session.UserId, session.Username)
+	if err != nil {
+		log.Printf("Error with passkey registration:%s", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		PublicKey webauthn.CreationOptions `json:"publicKey"`
+	}{options})
+}
+
+func (hh *HttpHandler) HandlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Sign in first")
+		return
+	}
+
+	var body struct {
+		Name       string                       `json:"name"`
+		Credential webauthn.AttestationResponse `json:"credential"`
+	}
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebAuthnBody)).Decode(&body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	err = hh.business.FinishPasskeyRegistration(session.UserId, body.Name, body.Credential)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPasskey) {
+			writeJSONError(w, http.StatusBadRequest, "The passkey could not be verified, try again")
+			return
+		}
+		log.Printf("Error with passkey registration:%s", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Redirect string `json:"redirect"`
+	}{"/settings/passkeys"})
+}
+
+func (hh *HttpHandler) HandlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	options, err := hh.business.BeginPasskeyLogin()
+	if err != nil {
+		log.Printf("Error with passkey login:%s", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		PublicKey webauthn.RequestOptions `json:"publicKey"`
+	}{options})
+}
+
+func (hh *HttpHandler) HandlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+
+	var credential webauthn.AssertionResponse
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebAuthnBody)).Decode(&credential)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	sessionId, err := hh.business.FinishPasskeyLogin(credential, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPasskey) {
+			writeJSONError(w, http.StatusBadRequest, "Passkey sign-in failed")
+			return
+		}
+		if message, ok := bannedMessage(err); ok {
+			writeJSONError(w, http.StatusForbidden, message)
+			return
+		}
+		log.Printf("Error with passkey login:%s", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	session, err := hh.business.Session(sessionId.String())
+	if err != nil {
+		log.Printf("Error with passkey login:%s", err)
+		writeJSONError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	setSessionCookie(w, session.SessionId, session.ExpiritionDate)
+	middleware.ResetCSRF(w)
+	writeJSON(w, http.StatusOK, struct {
+		Redirect string `json:"redirect"`
+	}{"/"})
+}
+
+// HandlePasskeySettings lists a user's passkeys and renames or removes them.
+// New passkeys are added from this page through the /webauthn/ endpoints.
+func (hh *HttpHandler) HandlePasskeySettings(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		id, err := strconv.Atoi(r.PostFormValue("id"))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		switch r.PostFormValue("action") {
+		case "rename":
+			err = hh.business.RenamePasskey(session.UserId, id, r.PostFormValue("name"))
+		case "delete":
+			err = hh.business.DeletePasskey(session.UserId, id)
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err != nil && !errors.Is(err, domain.ErrPasskeyNotFound) {
+			log.Printf("Error with passkey settings:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/settings/passkeys", http.StatusSeeOther)
+	} else if r.Method == http.MethodGet {
+		passkeys, err := hh.business.GetPasskeys(session.UserId)
+		if err != nil {
+			log.Printf("Error with passkey settings:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderPasskeysPage(w, r, session.Username, passkeys)
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("Error writing response:%s", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{message})
+}
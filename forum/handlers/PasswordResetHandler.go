@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+func (hh *HttpHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		email := strings.TrimSpace(r.FormValue("email"))
+		if email == "" {
+			internal.RenderForgotPage(w, r, "", "Email is required")
+			return
+		}
+
+		err := hh.business.RequestPasswordReset(email)
+		if err != nil {
+			log.Printf("Error with password reset request:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderForgotPage(w, r, "If an account uses this email, a reset link has been sent to it.", "")
+	} else if r.Method == http.MethodGet {
+		internal.RenderForgotPage(w, r, "", "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		token := r.FormValue("token")
+		password := r.FormValue("password")
+		if password != r.FormValue("confirm") {
+			internal.RenderResetPage(w, r, token, "Passwords do not match")
+			return
+		}
+
+		err := hh.business.ResetPassword(token, password)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidResetToken) {
+				internal.RenderResetPage(w, r, "", "This reset link is invalid or has expired")
+				return
+			}
+			if errors.Is(err, domain.ErrInvalidPassword) {
+				internal.RenderResetPage(w, r, token, "Password must be between 6 and 30 characters")
+				return
+			}
+			log.Printf("Error with password reset:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		ClearSession(w)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	} else if r.Method == http.MethodGet {
+		token := r.URL.Query().Get("token")
+		err := hh.business.CheckPasswordResetToken(token)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidResetToken) {
+				internal.RenderResetPage(w, r, "", "This reset link is invalid or has expired")
+				return
+			}
+			log.Printf("Error with password reset:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderResetPage(w, r, token, "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// maxPreviewBody bounds the JSON a preview request may send.
+const maxPreviewBody = 64 << 10
+
+// HandlePreview renders the Markdown of a post or comment being written, for
+// static/preview.js to show under the form. It answers {"html": ...} with
+// the HTML the text would be shown as.
+func (hh *HttpHandler) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+	_, err := hh.GetUsername(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Sign in first")
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPreviewBody)).Decode(&body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		HTML string `json:"html"`
+	}{hh.business.PreviewMarkdown(body.Text)})
+}
@@ -20,12 +20,6 @@ func (hh *HttpHandler) HandleLikeDislikePost(w http.ResponseWriter, r *http.Requ
 
 	postIDStr := r.FormValue("post_id")
 
-	ownerID, err := strconv.Atoi(r.FormValue("owner_id"))
-	if err != nil {
-		fmt.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
-	}
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
 		http.Error(w, "Bad Request", http.StatusBadRequest)
@@ -36,17 +30,16 @@ func (hh *HttpHandler) HandleLikeDislikePost(w http.ResponseWriter, r *http.Requ
 
 	switch action {
 	case "like":
-		err = hh.business.LikePost(postID, session.UserId, ownerID, "like your post", session.Username)
+		err = hh.business.LikePost(session, postID, "like your post")
 	case "dislike":
-		err = hh.business.DislikePost(postID, session.UserId, ownerID, "dislike your post", session.Username)
+		err = hh.business.DislikePost(session, postID, "dislike your post")
 	default:
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
 	if err != nil {
-		hh.Handle404(w, r)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		hh.actionFailed(w, r, err, "reaction")
 		return
 	}
 
@@ -66,18 +59,6 @@ func (hh *HttpHandler) HandleLikeDislikeComment(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	ownerID, err := strconv.Atoi(r.FormValue("owner_id"))
-	if err != nil {
-		fmt.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
-	}
-	postID, err := strconv.Atoi(r.FormValue("post_id"))
-	if err != nil {
-		fmt.Println(err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
-	}
 	commentIDStr := r.FormValue("comment_id")
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil {
@@ -90,20 +71,19 @@ func (hh *HttpHandler) HandleLikeDislikeComment(w http.ResponseWriter, r *http.R
 
 	switch action {
 	case "like":
-		err = hh.business.LikeComment(commentID, session.UserId, ownerID, "likes your comment", session.Username, postID)
+		err = hh.business.LikeComment(session, commentID, "likes your comment")
 		if err != nil {
 			fmt.Println(err)
 		}
 	case "dislike":
-		err = hh.business.DislikeComment(commentID, session.UserId, ownerID, "dislikes your comment", session.Username, postID)
+		err = hh.business.DislikeComment(session, commentID, "dislikes your comment")
 	default:
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
 	if err != nil {
-		hh.Handle404(w, r)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		hh.actionFailed(w, r, err, "reaction")
 		return
 	}
 
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+// HandleSavedSearches lists the saved searches of the user and saves,
+// updates and deletes them.
+func (hh *HttpHandler) HandleSavedSearches(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		notify, digest := r.PostFormValue("notify") != "", r.PostFormValue("digest") != ""
+		action := r.PostFormValue("action")
+		if action == "save" {
+			err = hh.business.SaveSearch(session.UserId, r.PostFormValue("name"), r.PostFormValue("q"), notify, digest)
+		} else {
+			id, convErr := strconv.Atoi(r.PostFormValue("id"))
+			if convErr != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			switch action {
+			case "update":
+				err = hh.business.UpdateSavedSearch(session.UserId, id, notify, digest)
+			case "delete":
+				err = hh.business.DeleteSavedSearch(session.UserId, id)
+			default:
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+		}
+		switch {
+		case err == nil, errors.Is(err, domain.ErrSavedSearchNotFound):
+			http.Redirect(w, r, "/search/saved", http.StatusSeeOther)
+		case errors.Is(err, domain.ErrTooManySavedSearches), errors.Is(err, domain.ErrEmptySearch),
+			errors.Is(err, domain.ErrSearchTooLong), errors.Is(err, domain.ErrInvalidSearch), errors.Is(err, domain.ErrInvalidTag):
+			w.WriteHeader(http.StatusBadRequest)
+			hh.renderSavedSearches(w, r, session, err.Error())
+		default:
+			log.Printf("Error with saved searches:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	} else if r.Method == http.MethodGet {
+		hh.renderSavedSearches(w, r, session, "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) renderSavedSearches(w http.ResponseWriter, r *http.Request, session *domain.Session, errorMessage string) {
+	searches, err := hh.business.GetSavedSearches(session.UserId)
+	if err != nil {
+		log.Printf("Error with saved searches:%s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	internal.RenderSavedSearchesPage(w, r, session.Username, searches, errorMessage)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+// searchResult is a search result in the JSON answer; Snippet is HTML with
+// the matching words in <mark> elements.
+type searchResult struct {
+	PostId    int       `json:"post_id"`
+	CommentId int       `json:"comment_id,omitempty"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Username  string    `json:"username"`
+	Date      time.Time `json:"date"`
+}
+
+// HandleSearch searches the posts, or with type=comments the comments, with
+// the query of the q parameter. With format=json it answers with the page of
+// results and the links to the pages around it.
+func (hh *HttpHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	session, _ := hh.GetUsername(w, r)
+	params := r.URL.Query()
+	export := params.Get("format") == "json"
+	query := domain.SearchQuery{Text: params.Get("q"), Kind: domain.SearchPosts}
+	if params.Get("type") == string(domain.SearchComments) {
+		query.Kind = domain.SearchComments
+	}
+
+	results, page, err := hh.business.Search(session, query, internal.PageRequest(r, ""))
+	if err != nil {
+		var errorMessage string
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, domain.ErrEmptySearch):
+			if !export {
+				internal.RenderSearchPage(w, r, session, query, nil, domain.Page{}, "")
+				return
+			}
+			errorMessage = err.Error()
+		case errors.Is(err, domain.ErrSearchTooLong), errors.Is(err, domain.ErrInvalidSearch), errors.Is(err, domain.ErrInvalidTag):
+			errorMessage = err.Error()
+		case errors.Is(err, domain.ErrSearchUnavailable):
+			errorMessage = err.Error()
+			status = http.StatusServiceUnavailable
+		default:
+			if export {
+				writeJSONError(w, http.StatusInternalServerError, "could not search")
+				return
+			}
+			hh.actionFailed(w, r, err, "search")
+			return
+		}
+		if export {
+			writeJSONError(w, status, errorMessage)
+			return
+		}
+		w.WriteHeader(status)
+		internal.RenderSearchPage(w, r, session, query, nil, domain.Page{}, errorMessage)
+		return
+	}
+
+	if export {
+		pager := internal.PagerFor(r, page, "")
+		exported := make([]searchResult, 0, len(results))
+		for _, res := range results {
+			exported = append(exported, searchResult{res.PostId, res.CommentId, res.Title, string(internal.Highlight(res.Snippet)), res.Username, res.CreationDate})
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Results []searchResult `json:"results"`
+			Prev    string         `json:"prev,omitempty"`
+			Next    string         `json:"next,omitempty"`
+		}{exported, pager.Prev, pager.Next})
+		return
+	}
+	internal.RenderSearchPage(w, r, session, query, results, page, "")
+}
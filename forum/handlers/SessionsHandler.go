@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+func (hh *HttpHandler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		switch r.PostFormValue("action") {
+		case "revoke":
+			id, err := strconv.Atoi(r.PostFormValue("id"))
+			if err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			err = hh.business.RevokeSession(session.UserId, id)
+			if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+				fmt.Println(err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if id == session.Id {
+				ClearSession(w)
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+		case "revoke_others":
+			err = hh.business.RevokeOtherSessions(session.UserId, session.SessionId)
+			if err != nil {
+				fmt.Println(err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+	} else if r.Method == http.MethodGet {
+		sessions, err := hh.business.GetSessions(session.UserId)
+		if err != nil {
+			fmt.Println(err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderSessionsPage(w, r, session, sessions)
+	} else {
+		w.WriteHeader(405)
+	}
+}
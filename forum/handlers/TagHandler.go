@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+// HandleTagPosts lists the posts carrying the tag named in the path,
+// /tag/{name}.
+func (hh *HttpHandler) HandleTagPosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/tag/")
+	session, _ := hh.GetUsername(w, r)
+	posts, page, err := hh.business.GetPostsByTag(session, name, internal.PageRequest(r, ""))
+	if err != nil {
+		hh.actionFailed(w, r, err, "tag posts")
+		return
+	}
+	hh.renderMainPage(w, r, session, posts, page, domain.PostFilter{Tag: name})
+}
+
+// HandleTags shows the tag cloud. Moderators also get the list of every tag
+// with actions to ban, unban and merge them.
+func (hh *HttpHandler) HandleTags(w http.ResponseWriter, r *http.Request) {
+	session, _ := hh.GetUsername(w, r)
+	if r.Method == http.MethodPost {
+		err := hh.business.Authorize(session, domain.ActionManageTags, 0)
+		if err != nil {
+			hh.Handle403(w, r)
+			return
+		}
+		tag := r.PostFormValue("tag")
+		var message string
+		switch r.PostFormValue("action") {
+		case "ban":
+			err = hh.business.BanTag(session, tag, true)
+			message = fmt.Sprintf("%s is banned.", tag)
+		case "unban":
+			err = hh.business.BanTag(session, tag, false)
+			message = fmt.Sprintf("%s is allowed again.", tag)
+		case "merge":
+			into := r.PostFormValue("into")
+			err = hh.business.MergeTags(session, tag, into)
+			message = fmt.Sprintf("%s has been merged into %s.", tag, into)
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrTagNotFound) || errors.Is(err, domain.ErrInvalidTag) || errors.Is(err, domain.ErrMergeSameTag) {
+				hh.renderTags(w, r, session, "", err.Error())
+				return
+			}
+			hh.actionFailed(w, r, err, "tag action")
+			return
+		}
+		hh.renderTags(w, r, session, message, "")
+	} else if r.Method == http.MethodGet {
+		hh.renderTags(w, r, session, "", "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) renderTags(w http.ResponseWriter, r *http.Request, session *domain.Session, message, errorMessage string) {
+	cloud, err := hh.business.GetTagCloud(session)
+	if err != nil {
+		hh.actionFailed(w, r, err, "tag cloud")
+		return
+	}
+	var managed []domain.Tag
+	if hh.business.Authorize(session, domain.ActionManageTags, 0) == nil {
+		managed, err = hh.business.GetTags(session)
+		if err != nil {
+			hh.actionFailed(w, r, err, "tags")
+			return
+		}
+	}
+	internal.RenderTagsPage(w, r, session, cloud, managed, message, errorMessage)
+}
+
+// HandleTagSuggest answers the tag autocomplete of the post forms with a
+// JSON list of tags starting with the q parameter.
+func (hh *HttpHandler) HandleTagSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	names, err := hh.business.SuggestTags(r.URL.Query().Get("q"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "could not look up tags")
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, names)
+}
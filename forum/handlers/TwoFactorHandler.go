@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+func (hh *HttpHandler) HandleTwoFactorSettings(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err != nil {
+		http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		code := r.PostFormValue("code")
+		var codes []string
+
+		switch r.PostFormValue("action") {
+		case "begin":
+			err = hh.business.BeginTwoFactorSetup(session.UserId)
+		case "confirm":
+			codes, err = hh.business.ConfirmTwoFactorSetup(session.UserId, code)
+		case "disable":
+			err = hh.business.DisableTwoFactor(session.UserId, code)
+		case "regenerate":
+			codes, err = hh.business.RegenerateRecoveryCodes(session.UserId, code)
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		errorMessage := ""
+		if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			errorMessage = "Invalid authentication code"
+		} else if err != nil {
+			log.Printf("Error with two-factor settings:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if errorMessage == "" && codes == nil {
+			http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+			return
+		}
+
+		status, err := hh.business.GetTwoFactorStatus(session.UserId, session.Username)
+		if err != nil {
+			log.Printf("Error with two-factor settings:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderTwoFactorPage(w, r, session.Username, status, codes, errorMessage)
+	} else if r.Method == http.MethodGet {
+		status, err := hh.business.GetTwoFactorStatus(session.UserId, session.Username)
+		if err != nil {
+			log.Printf("Error with two-factor settings:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderTwoFactorPage(w, r, session.Username, status, nil, "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+func (hh *HttpHandler) HandleAdminTwoFactor(w http.ResponseWriter, r *http.Request) {
+	session, err := hh.GetUsername(w, r)
+	if err == nil {
+		err = hh.business.Authorize(session, domain.ActionManageUsers, 0)
+	}
+	if err != nil {
+		hh.Handle403(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		username := strings.TrimSpace(r.PostFormValue("username"))
+		err = hh.business.ResetTwoFactor(session, username)
+		if err != nil {
+			if errors.Is(err, domain.ErrForbidden) {
+				hh.Handle403(w, r)
+				return
+			}
+			if errors.Is(err, domain.ErrInvalidUser) {
+				internal.RenderAdminTwoFactorPage(w, r, session.Username, "", "No user named "+username)
+				return
+			}
+			log.Printf("Error resetting two-factor:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderAdminTwoFactorPage(w, r, session.Username, "Two-factor authentication of "+username+" has been reset.", "")
+	} else if r.Method == http.MethodGet {
+		internal.RenderAdminTwoFactorPage(w, r, session.Username, "", "")
+	} else {
+		w.WriteHeader(405)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"forum/forum/domain"
+	"forum/forum/internal"
+)
+
+func (hh *HttpHandler) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		session, err := hh.GetUsername(w, r)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		err = hh.business.ResendVerification(session.UserId)
+		if err != nil {
+			log.Printf("Error resending verification:%s", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		internal.RenderVerifyPage(w, r, "A new verification link has been sent to your email address.", false)
+	} else if r.Method == http.MethodGet {
+		token := r.URL.Query().Get("token")
+		if token != "" {
+			err := hh.business.VerifyEmail(token)
+			if err != nil {
+				if errors.Is(err, domain.ErrInvalidVerifyToken) {
+					internal.RenderVerifyPage(w, r, "This verification link is invalid or has expired.", false)
+					return
+				}
+				log.Printf("Error verifying email:%s", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			internal.RenderVerifyPage(w, r, "Your email address has been verified.", false)
+			return
+		}
+
+		if r.URL.Query().Get("sent") != "" {
+			internal.RenderVerifyPage(w, r, "Your account has been created. We sent a verification link to your email address.", false)
+			return
+		}
+		session, err := hh.GetUsername(w, r)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if session.EmailVerified {
+			internal.RenderVerifyPage(w, r, "Your email address has been verified.", false)
+			return
+		}
+		internal.RenderVerifyPage(w, r, "You need to verify your email address before you can do this. Check your inbox for the verification link.", true)
+	} else {
+		w.WriteHeader(405)
+	}
+}
+
+// checkPermission answers the request with actionFailed if the user may not
+// perform action and reports whether the handler may continue.
+func (hh *HttpHandler) checkPermission(w http.ResponseWriter, r *http.Request, session *domain.Session, action domain.Action) bool {
+	err := hh.business.Authorize(session, action, 0)
+	if err == nil {
+		return true
+	}
+	hh.actionFailed(w, r, err, "permission check")
+	return false
+}
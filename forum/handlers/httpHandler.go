@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -27,8 +29,38 @@ func (hh *HttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.URL.Path {
 	case "/login":
 		hh.HandleUserLogin(w, r)
+	case "/login/2fa":
+		hh.HandleTwoFactorLogin(w, r)
+	case "/settings/2fa":
+		hh.HandleTwoFactorSettings(w, r)
+	case "/admin":
+		hh.HandleAdmin(w, r)
+	case "/admin/audit":
+		hh.HandleAuditLog(w, r)
+	case "/admin/categories":
+		hh.HandleAdminCategories(w, r)
+	case "/admin/2fa":
+		hh.HandleAdminTwoFactor(w, r)
+	case "/settings/accounts":
+		hh.HandleAccounts(w, r)
+	case "/settings/passkeys":
+		hh.HandlePasskeySettings(w, r)
+	case "/webauthn/register/begin":
+		hh.HandlePasskeyRegisterBegin(w, r)
+	case "/webauthn/register/finish":
+		hh.HandlePasskeyRegisterFinish(w, r)
+	case "/webauthn/login/begin":
+		hh.HandlePasskeyLoginBegin(w, r)
+	case "/webauthn/login/finish":
+		hh.HandlePasskeyLoginFinish(w, r)
 	case "/register":
 		hh.HandleUserRegistration(w, r)
+	case "/forgot":
+		hh.HandleForgotPassword(w, r)
+	case "/reset":
+		hh.HandleResetPassword(w, r)
+	case "/verify":
+		hh.HandleVerify(w, r)
 
 	case "/":
 		hh.MainHandler(w, r)
@@ -48,6 +80,16 @@ func (hh *HttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		hh.UserActivityHandler(w, r)
 	case "/filtered-posts":
 		hh.HandleFilteredPosts(w, r)
+	case "/tags":
+		hh.HandleTags(w, r)
+	case "/tags/suggest":
+		hh.HandleTagSuggest(w, r)
+	case "/search":
+		hh.HandleSearch(w, r)
+	case "/search/saved":
+		hh.HandleSavedSearches(w, r)
+	case "/preview":
+		hh.HandlePreview(w, r)
 	case "/like_dislike_post":
 		hh.HandleLikeDislikePost(w, r)
 	case "/like_dislike_comment":
@@ -56,11 +98,25 @@ func (hh *HttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		hh.HandleDislikedPosts(w, r)
 	case "/delete_comment":
 		hh.DeleteCommentHandler(w, r)
+	case "/report":
+		hh.HandleReport(w, r)
+	case "/moderation":
+		hh.HandleModeration(w, r)
+	case "/sessions":
+		hh.HandleSessions(w, r)
 	case "/exit":
 		hh.HandleLogout(w, r)
 	case "/access_denied":
 		hh.Handle403(w, r)
 	default:
+		if strings.HasPrefix(r.URL.Path, "/auth/") {
+			hh.HandleOAuth(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/tag/") {
+			hh.HandleTagPosts(w, r)
+			return
+		}
 		if strings.HasPrefix(r.URL.Path, "/post/") {
 			hh.HandlePostDetails(w, r)
 			return
@@ -126,11 +182,26 @@ func (hh *HttpHandler) GetUsername(w http.ResponseWriter, r *http.Request) (*dom
 
 	session, err := hh.business.Session(sessionID)
 	if err != nil {
+		if errors.Is(err, domain.ErrSessionExpired) {
+			ClearSession(w)
+		}
 		return nil, err
 	}
+	setSessionCookie(w, session.SessionId, session.ExpiritionDate)
 	return session, nil
 }
 
+func clientInfo(r *http.Request) domain.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return domain.Client{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
+
 func formatTimestamp(timestamp time.Time) string {
 	T := timestamp.Format("2006-01-02 15:04:05")
 
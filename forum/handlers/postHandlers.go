@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -30,7 +31,9 @@ func (hh *HttpHandler) HandleNewPost(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		category := r.FormValue("category")
+		if !hh.checkPermission(w, r, session, domain.ActionPost) {
+			return
+		}
 		title := r.FormValue("title")
 		content := r.FormValue("content")
 
@@ -67,7 +70,7 @@ func (hh *HttpHandler) HandleNewPost(w http.ResponseWriter, r *http.Request) {
 
 			const maxFileSize = 20 << 20
 			if fileSize > maxFileSize {
-				internal.RenderPostPage(w, r, session.Username, "Image file size exceeds the limit (20MB) ")
+				hh.renderPostPage(w, r, session.Username, "Image file size exceeds the limit (20MB) ", formCategories(r), formTags(r))
 				return
 			}
 
@@ -95,25 +98,28 @@ func (hh *HttpHandler) HandleNewPost(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
-		if len(strings.TrimSpace(category)) <= 0 || len(strings.TrimSpace(title)) <= 0 {
-			internal.RenderPostPage(w, r, session.Username, "The post title and content must not be empty")
+		if len(strings.TrimSpace(title)) <= 0 {
+			hh.renderPostPage(w, r, session.Username, "The post title and content must not be empty", formCategories(r), formTags(r))
 			return
 		}
 		newPost := domain.Posts{
 			Username:     session.Username,
 			UserId:       session.UserId,
-			Category:     category,
+			Categories:   formCategories(r),
+			Tags:         formTags(r),
 			Title:        title,
 			Content:      content,
-			CategoryId:   1,
 			ImageField:   imagePathHTML,
 			CreationDate: time.Now(),
 		}
 
-		err = hh.business.Post(newPost)
+		err = hh.business.Post(session, newPost)
 		if err != nil {
-
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			if postFormError(err) {
+				hh.renderPostPage(w, r, session.Username, err.Error(), newPost.Categories, newPost.Tags)
+				return
+			}
+			hh.actionFailed(w, r, err, "new post")
 			return
 		}
 
@@ -125,25 +131,67 @@ func (hh *HttpHandler) HandleNewPost(w http.ResponseWriter, r *http.Request) {
 			hh.Handle404(w, r)
 			return
 		}
-		posts, err := hh.business.GetAllPosts()
+		posts, page, err := hh.business.GetAllPosts(username, domain.PostFilter{}, internal.PageRequest(r, ""))
 		if err != nil {
 			fmt.Println("Cant get Posts")
 			return
 		}
 		if username.Username == "" {
 
-			internal.RenderMainPage(w, r, username, posts)
+			hh.renderMainPage(w, r, username, posts, page, domain.PostFilter{})
+			return
+		}
+		if !hh.checkPermission(w, r, username, domain.ActionPost) {
 			return
 		}
 
-		internal.RenderPostPage(w, r, username.Username, "")
+		hh.renderPostPage(w, r, username.Username, "", nil, nil)
 	} else {
 		w.WriteHeader(405)
 	}
 }
 
+// formCategories returns the categories ticked in a post form. Only their
+// slugs are set; Business looks up the rest.
+func formCategories(r *http.Request) []domain.Category {
+	var categories []domain.Category
+	for _, slug := range r.Form["category"] {
+		categories = append(categories, domain.Category{Slug: slug})
+	}
+	return categories
+}
+
+// formTags returns the tags typed into the comma separated tags field.
+func formTags(r *http.Request) []string {
+	return strings.Split(r.FormValue("tags"), ",")
+}
+
+// postFormError reports whether err is a mistake in the post form, shown
+// above the form rather than as an error page.
+func postFormError(err error) bool {
+	var rule *domain.CategoryRuleError
+	var banned *domain.BannedTagError
+	return errors.Is(err, domain.ErrNoCategory) || errors.Is(err, domain.ErrInvalidCategory) || errors.As(err, &rule) ||
+		errors.Is(err, domain.ErrInvalidTag) || errors.Is(err, domain.ErrTooManyTags) || errors.As(err, &banned)
+}
+
+func (hh *HttpHandler) renderPostPage(w http.ResponseWriter, r *http.Request, username, errorMessage string, chosen []domain.Category, tags []string) {
+	categories, err := hh.business.GetCategories()
+	if err != nil {
+		log.Printf("Error getting categories:%s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	internal.RenderPostPage(w, r, username, errorMessage, categories, chosen, tags)
+}
+
 func (hh *HttpHandler) HandleMyPosts(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
+		session, err := hh.GetUsername(w, r)
+		if err != nil {
+			http.Redirect(w, r, "/access_denied", http.StatusSeeOther)
+			return
+		}
 		method := r.PostFormValue("delete_method")
 		if method == "DELETE" {
 			postID := r.PostFormValue("id")
@@ -158,34 +206,31 @@ func (hh *HttpHandler) HandleMyPosts(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			err = hh.business.DeletePost(id)
+			err = hh.business.DeletePost(session, id)
 			if err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				hh.actionFailed(w, r, err, "delete post")
 				return
 			}
 			http.Redirect(w, r, "/my_posts", http.StatusSeeOther)
 			return
 		}
 	} else if r.Method == http.MethodGet {
-		sessionCookie, err := r.Cookie("session_id")
+		session, err := hh.GetUsername(w, r)
 		if err != nil {
-			hh.Handle404(w, r)
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
-		sessionID := sessionCookie.Value
-
-		session, err := hh.business.Session(sessionID)
-		posts, err := hh.business.GetMyPosts(session.UserId)
+		posts, page, err := hh.business.GetMyPosts(session.UserId, internal.PageRequest(r, ""))
 		if err != nil {
 			fmt.Println(err)
 			fmt.Println("Can't get Posts")
 			return
 		}
 		if session.Username == "" {
-			internal.RenderMyPostPage(w, r, "", posts)
+			internal.RenderMyPostPage(w, r, "", posts, page)
 			return
 		}
-		internal.RenderMyPostPage(w, r, session.Username, posts)
+		internal.RenderMyPostPage(w, r, session.Username, posts, page)
 	} else {
 		w.WriteHeader(405)
 	}
@@ -206,22 +251,31 @@ func (hh *HttpHandler) HandlePostDetails(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		comments, err := hh.business.GetComments(postID)
-		if err != nil {
+		username, err := hh.GetUsername(w, r)
+		if (post.Hidden || post.Shadowed) && hh.business.Authorize(username, domain.ActionViewHidden, post.UserId) != nil {
+			hh.Handle404(w, r)
+			return
+		}
+		comments, commentsErr := hh.business.GetComments(username, postID)
+		if commentsErr != nil {
 			http.Error(w, "Bad Request", http.StatusNotFound)
 			return
 		}
-		username, err := hh.GetUsername(w, r)
+		commentsClosed := ""
+		var ruleErr *domain.CategoryRuleError
+		if errors.As(hh.business.CheckCategoryRules(username, post.Categories, domain.ActionComment), &ruleErr) {
+			commentsClosed = ruleErr.Error()
+		}
 		if err != nil {
 			if errors.Is(err, domain.ErrSessionNotFound) {
-				internal.RenderAboutPage(w, r, username, post, comments)
+				internal.RenderAboutPage(w, r, username, post, comments, commentsClosed)
 
 				return
 			}
-			internal.RenderAboutPage(w, r, username, post, comments)
+			internal.RenderAboutPage(w, r, username, post, comments, commentsClosed)
 			return
 		}
-		internal.RenderAboutPage(w, r, username, post, comments)
+		internal.RenderAboutPage(w, r, username, post, comments, commentsClosed)
 	} else {
 		w.WriteHeader(405)
 	}
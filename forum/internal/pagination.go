@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"forum/forum/domain"
+)
+
+// Pager holds the links to the pages before and after a page of a list;
+// an empty link means there is no page on that side.
+type Pager struct {
+	Prev string
+	Next string
+}
+
+// PageRequest reads the page of a list a request asks for from its after,
+// before and size query parameters. Each name starts with prefix, so that a
+// page can show several lists. Malformed cursors ask for the first page.
+func PageRequest(r *http.Request, prefix string) domain.PageRequest {
+	query := r.URL.Query()
+	req := domain.PageRequest{}
+	req.Size, _ = strconv.Atoi(query.Get(prefix + "size"))
+	if cursor, ok := parseCursor(query.Get(prefix + "before")); ok {
+		req.Cursor = cursor
+		req.Backward = true
+	} else if cursor, ok := parseCursor(query.Get(prefix + "after")); ok {
+		req.Cursor = cursor
+	}
+	return req
+}
+
+// formatCursor writes a cursor as its id or, for lists ordered by a key,
+// as the key and the id separated by an underscore.
+func formatCursor(cursor domain.Cursor) string {
+	id := strconv.Itoa(cursor.Id)
+	if cursor.Key == 0 {
+		return id
+	}
+	return strconv.FormatFloat(cursor.Key, 'g', -1, 64) + "_" + id
+}
+
+// parseCursor reads a cursor written by formatCursor.
+func parseCursor(value string) (domain.Cursor, bool) {
+	var cursor domain.Cursor
+	var err error
+	if key, id, keyed := strings.Cut(value, "_"); keyed {
+		cursor.Key, err = strconv.ParseFloat(key, 64)
+		if err != nil || math.IsNaN(cursor.Key) || math.IsInf(cursor.Key, 0) {
+			return domain.Cursor{}, false
+		}
+		value = id
+	}
+	cursor.Id, err = strconv.Atoi(value)
+	return cursor, err == nil && cursor.Id > 0
+}
+
+// PagerFor links to the pages around page, keeping the rest of the query of
+// the request, such as filters and the cursors of other lists.
+func PagerFor(r *http.Request, page domain.Page, prefix string) Pager {
+	link := func(param string, cursor *domain.Cursor) string {
+		if cursor == nil {
+			return ""
+		}
+		query := r.URL.Query()
+		query.Del(prefix + "after")
+		query.Del(prefix + "before")
+		query.Set(prefix+param, formatCursor(*cursor))
+		return r.URL.Path + "?" + query.Encode()
+	}
+	return Pager{Prev: link("before", page.Prev), Next: link("after", page.Next)}
+}
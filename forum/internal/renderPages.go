@@ -4,28 +4,54 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"forum/forum/domain"
 )
 
-func RenderMainPage(w http.ResponseWriter, r *http.Request, userSession *domain.Session, posts []domain.Posts) {
-	tmpl, err := template.ParseFiles("./forum/templates/index.html", "./forum/templates/base.html")
+func RenderMainPage(w http.ResponseWriter, r *http.Request, userSession *domain.Session, posts []domain.Posts, page domain.Page, categories []domain.Category, filter domain.PostFilter) {
+	tmpl, err := parseTemplates(r, "./forum/templates/index.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	if userSession != nil {
-	}
 	data := struct {
-		Name  string
-		Posts []domain.Posts
+		Name       string
+		Moderator  bool
+		Admin      bool
+		Posts      []domain.Posts
+		Categories []domain.Category
+		Checked    map[string]bool
+		MatchAll   bool
+		Tag        string
+		Sort       domain.PostSort
+		Period     domain.Period
+		Sorts      []SortLink
+		Periods    []SortLink
+		Pager      Pager
 	}{
-		Posts: posts,
+		Posts:      posts,
+		Categories: categories,
+		Checked:    make(map[string]bool),
+		MatchAll:   filter.MatchAll,
+		Tag:        filter.Tag,
+		Sort:       filter.Sort,
+		Period:     filter.Period,
+		Pager:      PagerFor(r, page, ""),
+	}
+	for _, slug := range filter.Categories {
+		data.Checked[slug] = true
+	}
+	if filter.Tag == "" {
+		data.Sorts, data.Periods = sortLinks(r, filter)
 	}
 	if userSession == nil {
 		data.Name = "Guest"
 	} else {
 		data.Name = userSession.Username
+		data.Moderator = userSession.Role.AtLeast(domain.RoleModerator)
+		data.Admin = userSession.Role.AtLeast(domain.RoleAdmin)
 	}
 
 	err = tmpl.Execute(w, data)
@@ -36,8 +62,8 @@ func RenderMainPage(w http.ResponseWriter, r *http.Request, userSession *domain.
 	}
 }
 
-func RenderUserActivityPage(w http.ResponseWriter, r *http.Request, username string, activity domain.UserActivity) {
-	tmpl, err := template.ParseFiles("./forum/templates/History.html", "./forum/templates/base.html")
+func RenderUserActivityPage(w http.ResponseWriter, r *http.Request, username string, activity domain.UserActivity, page domain.Page) {
+	tmpl, err := parseTemplates(r, "./forum/templates/History.html", "./forum/templates/base.html")
 	if err != nil {
 		fmt.Println("Cant get the HTML files")
 
@@ -47,13 +73,13 @@ func RenderUserActivityPage(w http.ResponseWriter, r *http.Request, username str
 
 	// Execute the template and write the response
 	data := struct {
-		Name         string
-		CreatedPosts []domain.Posts
-		Comments     []domain.Comments
+		Name     string
+		Comments []domain.Comments
+		Pager    Pager
 	}{
-		Name:         username,
-		CreatedPosts: activity.CreatedPosts,
-		Comments:     activity.Comments,
+		Name:     username,
+		Comments: activity.Comments,
+		Pager:    PagerFor(r, page, ""),
 	}
 
 	err = tmpl.Execute(w, data)
@@ -63,8 +89,8 @@ func RenderUserActivityPage(w http.ResponseWriter, r *http.Request, username str
 	}
 }
 
-func RenderNotifications(w http.ResponseWriter, username string, notifications []domain.Notification, notifications_comment []domain.Notification_comments) {
-	tmpl, err := template.ParseFiles("./forum/templates/notify.html", "./forum/templates/base.html")
+func RenderNotifications(w http.ResponseWriter, r *http.Request, username string, notifications []domain.Notification, page domain.Page, notifications_comment []domain.Notification_comments, commentsPage domain.Page, alerts []domain.SearchAlert, alertsPage domain.Page) {
+	tmpl, err := parseTemplates(r, "./forum/templates/notify.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -74,10 +100,18 @@ func RenderNotifications(w http.ResponseWriter, username string, notifications [
 		Name                  string
 		Notifications         []domain.Notification
 		Notification_comments []domain.Notification_comments
+		SearchAlerts          []domain.SearchAlert
+		Pager                 Pager
+		CommentsPager         Pager
+		AlertsPager           Pager
 	}{
 		Name:                  username,
 		Notifications:         notifications,
 		Notification_comments: notifications_comment,
+		SearchAlerts:          alerts,
+		Pager:                 PagerFor(r, page, ""),
+		CommentsPager:         PagerFor(r, commentsPage, "comments_"),
+		AlertsPager:           PagerFor(r, alertsPage, "alerts_"),
 	}
 
 	err = tmpl.Execute(w, data)
@@ -87,16 +121,18 @@ func RenderNotifications(w http.ResponseWriter, username string, notifications [
 	}
 }
 
-func RenderLoginPage(w http.ResponseWriter, r *http.Request, errorMessage string) {
-	tmpl, err := template.ParseFiles("./forum/templates/login.html")
+func RenderLoginPage(w http.ResponseWriter, r *http.Request, errorMessage string, providers []domain.IdentityProvider) {
+	tmpl, err := parseTemplates(r, "./forum/templates/login.html")
 	if err != nil {
 		fmt.Println("Cant get the HTML files")
 		return
 	}
 	data := struct {
 		ErrorMessage string
+		Providers    []domain.IdentityProvider
 	}{
 		ErrorMessage: errorMessage,
+		Providers:    providers,
 	}
 
 	err = tmpl.Execute(w, data)
@@ -107,16 +143,18 @@ func RenderLoginPage(w http.ResponseWriter, r *http.Request, errorMessage string
 	}
 }
 
-func RenderRegisterPage(w http.ResponseWriter, r *http.Request, errorMessage string) {
-	tmpl, err := template.ParseFiles("./forum/templates/register.html")
+func RenderRegisterPage(w http.ResponseWriter, r *http.Request, errorMessage string, providers []domain.IdentityProvider) {
+	tmpl, err := parseTemplates(r, "./forum/templates/register.html")
 	if err != nil {
 		fmt.Println("Cant get the HTML files")
 		return
 	}
 	data := struct {
 		ErrorMessage string
+		Providers    []domain.IdentityProvider
 	}{
 		ErrorMessage: errorMessage,
+		Providers:    providers,
 	}
 
 	err = tmpl.Execute(w, data)
@@ -126,8 +164,8 @@ func RenderRegisterPage(w http.ResponseWriter, r *http.Request, errorMessage str
 	}
 }
 
-func RenderLikePages(w http.ResponseWriter, r *http.Request, username string, posts []domain.Posts) {
-	tmpl, err := template.ParseFiles("./forum/templates/likedPosts.html", "./forum/templates/base.html")
+func RenderLikePages(w http.ResponseWriter, r *http.Request, username string, posts []domain.Posts, page domain.Page) {
+	tmpl, err := parseTemplates(r, "./forum/templates/likedPosts.html", "./forum/templates/base.html")
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("dada")
@@ -139,9 +177,11 @@ func RenderLikePages(w http.ResponseWriter, r *http.Request, username string, po
 	data := struct {
 		Username string
 		Posts    []domain.Posts
+		Pager    Pager
 	}{
 		Username: username,
 		Posts:    posts,
+		Pager:    PagerFor(r, page, ""),
 	}
 	err = tmpl.Execute(w, data)
 	if err != nil {
@@ -153,8 +193,8 @@ func RenderLikePages(w http.ResponseWriter, r *http.Request, username string, po
 	}
 }
 
-func RenderDislikePages(w http.ResponseWriter, r *http.Request, username string, dislikedposts []domain.Posts) {
-	tmpl, err := template.ParseFiles("./forum/templates/DislikedPosts.html", "./forum/templates/base.html")
+func RenderDislikePages(w http.ResponseWriter, r *http.Request, username string, dislikedposts []domain.Posts, page domain.Page) {
+	tmpl, err := parseTemplates(r, "./forum/templates/DislikedPosts.html", "./forum/templates/base.html")
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("dada")
@@ -166,9 +206,11 @@ func RenderDislikePages(w http.ResponseWriter, r *http.Request, username string,
 	data := struct {
 		Username     string
 		DislikedPost []domain.Posts
+		Pager        Pager
 	}{
 		Username:     username,
 		DislikedPost: dislikedposts,
+		Pager:        PagerFor(r, page, ""),
 	}
 	err = tmpl.Execute(w, data)
 	if err != nil {
@@ -180,19 +222,25 @@ func RenderDislikePages(w http.ResponseWriter, r *http.Request, username string,
 	}
 }
 
-func RenderPostPage(w http.ResponseWriter, r *http.Request, username string, error string) {
-	tmpl, err := template.ParseFiles("./forum/templates/createPost.html", "./forum/templates/base.html")
+func RenderPostPage(w http.ResponseWriter, r *http.Request, username string, error string, categories []domain.Category, chosen []domain.Category, tags []string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/createPost.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	data := struct {
-		Username string
-		Error    string
+		Username   string
+		Error      string
+		Categories []domain.Category
+		Checked    map[string]bool
+		Tags       string
 	}{
-		Username: username,
-		Error:    error,
+		Username:   username,
+		Error:      error,
+		Categories: categories,
+		Checked:    slugSet(chosen),
+		Tags:       tagList(tags),
 	}
 
 	err = tmpl.Execute(w, data)
@@ -202,21 +250,27 @@ func RenderPostPage(w http.ResponseWriter, r *http.Request, username string, err
 	}
 }
 
-func RenderEditPostPage(w http.ResponseWriter, r *http.Request, username string, error string, postID string) error {
-	tmpl, err := template.ParseFiles("./forum/templates/Edit_Post.html", "./forum/templates/base.html")
+func RenderEditPostPage(w http.ResponseWriter, r *http.Request, username string, error string, postID string, categories []domain.Category, chosen []domain.Category, tags []string) error {
+	tmpl, err := parseTemplates(r, "./forum/templates/Edit_Post.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return err
 	}
 
 	data := struct {
-		Username string
-		Error    string
-		PostId   string
+		Username   string
+		Error      string
+		PostId     string
+		Categories []domain.Category
+		Checked    map[string]bool
+		Tags       string
 	}{
-		Username: username,
-		Error:    error,
-		PostId:   postID,
+		Username:   username,
+		Error:      error,
+		PostId:     postID,
+		Categories: categories,
+		Checked:    slugSet(chosen),
+		Tags:       tagList(tags),
 	}
 	err = tmpl.Execute(w, data)
 	if err != nil {
@@ -227,7 +281,7 @@ func RenderEditPostPage(w http.ResponseWriter, r *http.Request, username string,
 }
 
 func RenderEditCommentPage(w http.ResponseWriter, r *http.Request, username string, error string, commentID string) error {
-	tmpl, err := template.ParseFiles("./forum/templates/Edit_Comment.html", "./forum/templates/base.html")
+	tmpl, err := parseTemplates(r, "./forum/templates/Edit_Comment.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return err
@@ -250,8 +304,8 @@ func RenderEditCommentPage(w http.ResponseWriter, r *http.Request, username stri
 	return nil
 }
 
-func RenderMyPostPage(w http.ResponseWriter, r *http.Request, username string, posts []domain.Posts) {
-	tmpl, err := template.ParseFiles("./forum/templates/my_posts.html", "./forum/templates/base.html")
+func RenderMyPostPage(w http.ResponseWriter, r *http.Request, username string, posts []domain.Posts, page domain.Page) {
+	tmpl, err := parseTemplates(r, "./forum/templates/my_posts.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -260,9 +314,11 @@ func RenderMyPostPage(w http.ResponseWriter, r *http.Request, username string, p
 	data := struct {
 		Username string
 		Posts    []domain.Posts
+		Pager    Pager
 	}{
 		Username: username,
 		Posts:    posts,
+		Pager:    PagerFor(r, page, ""),
 	}
 	err = tmpl.Execute(w, data)
 	if err != nil {
@@ -273,7 +329,7 @@ func RenderMyPostPage(w http.ResponseWriter, r *http.Request, username string, p
 }
 
 func RenderErrorPage(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("./forum/templates/404.html")
+	tmpl, err := parseTemplates(r, "./forum/templates/404.html")
 	if err != nil {
 		fmt.Println("Cant get the HTML files")
 		return
@@ -286,35 +342,37 @@ func RenderErrorPage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func RenderError403Page(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("./forum/templates/403.html")
+func RenderError403Page(w http.ResponseWriter, r *http.Request, message string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/403.html")
 	if err != nil {
 		fmt.Println("Cant get the HTML files")
 		return
 	}
 
-	err = tmpl.Execute(w, nil)
+	err = tmpl.Execute(w, message)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 }
 
-func RenderAboutPage(w http.ResponseWriter, r *http.Request, userSession *domain.Session, posts domain.Posts, comments []domain.Comments) {
-	tmpl, err := template.ParseFiles("./forum/templates/About.html", "./forum/templates/base.html")
+func RenderAboutPage(w http.ResponseWriter, r *http.Request, userSession *domain.Session, posts domain.Posts, comments []domain.Comments, commentsClosed string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/About.html", "./forum/templates/base.html")
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	data := struct {
-		Name     string
-		UserId   int
-		Post     domain.Posts
-		Comments []domain.Comments
+		Name           string
+		UserId         int
+		Post           domain.Posts
+		Comments       []domain.Comments
+		CommentsClosed string
 	}{
-		Post:     posts,
-		Comments: comments,
+		Post:           posts,
+		Comments:       comments,
+		CommentsClosed: commentsClosed,
 	}
 	if userSession == nil {
 		data.Name = "Guest"
@@ -331,3 +389,503 @@ func RenderAboutPage(w http.ResponseWriter, r *http.Request, userSession *domain
 		return
 	}
 }
+
+func RenderSessionsPage(w http.ResponseWriter, r *http.Request, current *domain.Session, sessions []domain.Session) {
+	tmpl, err := parseTemplates(r, "./forum/templates/sessions.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name      string
+		CurrentId int
+		Sessions  []domain.Session
+	}{
+		Name:      current.Username,
+		CurrentId: current.Id,
+		Sessions:  sessions,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderForgotPage(w http.ResponseWriter, r *http.Request, message string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/forgot.html")
+	if err != nil {
+		fmt.Println("Cant get the HTML files")
+		return
+	}
+	data := struct {
+		Message      string
+		ErrorMessage string
+	}{
+		Message:      message,
+		ErrorMessage: errorMessage,
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderResetPage(w http.ResponseWriter, r *http.Request, token string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/reset.html")
+	if err != nil {
+		fmt.Println("Cant get the HTML files")
+		return
+	}
+	data := struct {
+		Token        string
+		ErrorMessage string
+	}{
+		Token:        token,
+		ErrorMessage: errorMessage,
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderVerifyPage(w http.ResponseWriter, r *http.Request, message string, canResend bool) {
+	tmpl, err := parseTemplates(r, "./forum/templates/verify.html")
+	if err != nil {
+		fmt.Println("Cant get the HTML files")
+		return
+	}
+	data := struct {
+		Message   string
+		CanResend bool
+	}{
+		Message:   message,
+		CanResend: canResend,
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderTwoFactorLoginPage(w http.ResponseWriter, r *http.Request, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/login_2fa.html")
+	if err != nil {
+		fmt.Println("Cant get the HTML files")
+		return
+	}
+	data := struct {
+		ErrorMessage string
+	}{
+		ErrorMessage: errorMessage,
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderTwoFactorPage(w http.ResponseWriter, r *http.Request, username string, status domain.TwoFactorStatus, recoveryCodes []string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/twofactor.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// otpauth:// links are filtered by html/template unless marked safe.
+	data := struct {
+		Name          string
+		Status        domain.TwoFactorStatus
+		SetupLink     template.URL
+		RecoveryCodes []string
+		Error         string
+	}{
+		Name:          username,
+		Status:        status,
+		SetupLink:     template.URL(status.SetupURI),
+		RecoveryCodes: recoveryCodes,
+		Error:         errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderAdminTwoFactorPage(w http.ResponseWriter, r *http.Request, username string, message string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/admin_2fa.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name    string
+		Message string
+		Error   string
+	}{
+		Name:    username,
+		Message: message,
+		Error:   errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderPasskeysPage(w http.ResponseWriter, r *http.Request, username string, passkeys []domain.Passkey) {
+	tmpl, err := parseTemplates(r, "./forum/templates/passkeys.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name     string
+		Passkeys []domain.Passkey
+	}{
+		Name:     username,
+		Passkeys: passkeys,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderAccountsPage(w http.ResponseWriter, r *http.Request, username string, identities []domain.UserIdentity, providers []domain.IdentityProvider, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/accounts.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name       string
+		Identities []domain.UserIdentity
+		Providers  []domain.IdentityProvider
+		Error      string
+	}{
+		Name:       username,
+		Identities: identities,
+		Providers:  providers,
+		Error:      errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderReportPage(w http.ResponseWriter, r *http.Request, username string, post domain.Posts, comment *domain.Comments, message string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/report.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name    string
+		Post    domain.Posts
+		Comment *domain.Comments
+		Reasons []domain.ReportReason
+		Message string
+		Error   string
+	}{
+		Name:    username,
+		Post:    post,
+		Comment: comment,
+		Reasons: domain.ReportReasons,
+		Message: message,
+		Error:   errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderModerationPage(w http.ResponseWriter, r *http.Request, username string, items []domain.ModerationItem, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/moderation.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name  string
+		Items []domain.ModerationItem
+		Error string
+	}{
+		Name:  username,
+		Items: items,
+		Error: errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderAdminPage(w http.ResponseWriter, r *http.Request, username string, query string, users []domain.UserSummary, message string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/admin.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name    string
+		Query   string
+		Users   []domain.UserSummary
+		Roles   []domain.Role
+		Message string
+		Error   string
+	}{
+		Name:    username,
+		Query:   query,
+		Users:   users,
+		Roles:   domain.Roles,
+		Message: message,
+		Error:   errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderAdminCategoriesPage(w http.ResponseWriter, r *http.Request, username string, categories []domain.Category, message string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/admin_categories.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name         string
+		Categories   []domain.Category
+		PostPolicies []domain.PostPolicy
+		Message      string
+		Error        string
+	}{
+		Name:         username,
+		Categories:   categories,
+		PostPolicies: domain.PostPolicies,
+		Message:      message,
+		Error:        errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// tagCloudSizes is the number of font sizes in the tag cloud.
+const tagCloudSizes = 5
+
+func RenderTagsPage(w http.ResponseWriter, r *http.Request, userSession *domain.Session, cloud []domain.Tag, managed []domain.Tag, message string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/tags.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	type cloudTag struct {
+		domain.Tag
+		Size int
+	}
+	most := 1
+	for _, t := range cloud {
+		if t.PostCount > most {
+			most = t.PostCount
+		}
+	}
+	data := struct {
+		Name    string
+		Cloud   []cloudTag
+		Managed []domain.Tag
+		Message string
+		Error   string
+	}{
+		Name:    "Guest",
+		Managed: managed,
+		Message: message,
+		Error:   errorMessage,
+	}
+	for _, t := range cloud {
+		data.Cloud = append(data.Cloud, cloudTag{t, 1 + (tagCloudSizes-1)*t.PostCount/most})
+	}
+	if userSession != nil {
+		data.Name = userSession.Username
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderAuditLogPage(w http.ResponseWriter, r *http.Request, username string, query url.Values, entries []domain.AuditEntry, exportURL string, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/audit.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name      string
+		Query     url.Values
+		Entries   []domain.AuditEntry
+		Actions   []domain.AuditAction
+		Targets   []domain.AuditTarget
+		ExportURL string
+		Error     string
+	}{
+		Name:      username,
+		Query:     query,
+		Entries:   entries,
+		Actions:   domain.AuditActions,
+		Targets:   domain.AuditTargets,
+		ExportURL: exportURL,
+		Error:     errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// slugSet returns the slugs of categories for checking form boxes.
+func slugSet(categories []domain.Category) map[string]bool {
+	set := make(map[string]bool, len(categories))
+	for _, c := range categories {
+		set[c.Slug] = true
+	}
+	return set
+}
+
+// tagList writes tags as they are typed into the tags field of a post form.
+func tagList(tags []string) string {
+	var names []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			names = append(names, t)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// RenderSearchPage shows the search form and a page of what the search
+// found.
+func RenderSearchPage(w http.ResponseWriter, r *http.Request, userSession *domain.Session, query domain.SearchQuery, results []domain.SearchResult, page domain.Page, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/search.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	type searchResult struct {
+		domain.SearchResult
+		Highlighted template.HTML
+	}
+	data := struct {
+		Name     string
+		Query    string
+		Comments bool
+		Searched bool
+		Results  []searchResult
+		Error    string
+		Pager    Pager
+		// CanSave offers to save the search, which only finds new posts.
+		CanSave bool
+	}{
+		Name:     "Guest",
+		Query:    query.Text,
+		Comments: query.Kind == domain.SearchComments,
+		Searched: strings.TrimSpace(query.Text) != "" && errorMessage == "",
+		Error:    errorMessage,
+		Pager:    PagerFor(r, page, ""),
+	}
+	data.CanSave = userSession != nil && data.Searched && !data.Comments
+	for _, res := range results {
+		data.Results = append(data.Results, searchResult{res, Highlight(res.Snippet)})
+	}
+	if userSession != nil {
+		data.Name = userSession.Username
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func RenderSavedSearchesPage(w http.ResponseWriter, r *http.Request, username string, searches []domain.SavedSearch, errorMessage string) {
+	tmpl, err := parseTemplates(r, "./forum/templates/saved_searches.html", "./forum/templates/base.html")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Name     string
+		Searches []domain.SavedSearch
+		Error    string
+	}{
+		Name:     username,
+		Searches: searches,
+		Error:    errorMessage,
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
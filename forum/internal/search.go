@@ -0,0 +1,17 @@
+package internal
+
+import (
+	"html/template"
+	"strings"
+
+	"forum/forum/domain"
+)
+
+// Highlight escapes a search result snippet for HTML and marks the words
+// that matched the search.
+func Highlight(snippet string) template.HTML {
+	escaped := template.HTMLEscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, domain.HighlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, domain.HighlightEnd, "</mark>")
+	return template.HTML(escaped)
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"net/http"
+
+	"forum/forum/domain"
+)
+
+// PostSort reads the order a request asks posts in from its sort query
+// parameter and, for top posts, the period from its t parameter. Posts are
+// newest first by default, and top posts those of the past week.
+func PostSort(r *http.Request) (domain.PostSort, domain.Period) {
+	query := r.URL.Query()
+	sort := domain.PostSort(query.Get("sort"))
+	if !sort.Valid() {
+		sort = domain.SortNew
+	}
+	period := domain.Period(query.Get("t"))
+	if !period.Valid() {
+		period = domain.PeriodWeek
+	}
+	return sort, period
+}
+
+// SortLink is a link to the same list in another order, or over another
+// period.
+type SortLink struct {
+	Label  string
+	URL    string
+	Active bool
+}
+
+var sortLabels = map[domain.PostSort]string{
+	domain.SortNew:           "New",
+	domain.SortHot:           "Hot",
+	domain.SortTop:           "Top",
+	domain.SortControversial: "Controversial",
+	domain.SortComments:      "Most commented",
+}
+
+var periodLabels = map[domain.Period]string{
+	domain.PeriodDay:   "Today",
+	domain.PeriodWeek:  "This week",
+	domain.PeriodMonth: "This month",
+	domain.PeriodAll:   "All time",
+}
+
+// sortLinks links to the list of the request in every sort mode and, when
+// it shows top posts, over every period. The links keep the filters of the
+// request but start again from the first page.
+func sortLinks(r *http.Request, filter domain.PostFilter) (sorts, periods []SortLink) {
+	link := func(sort domain.PostSort, period domain.Period) string {
+		query := r.URL.Query()
+		query.Del("after")
+		query.Del("before")
+		query.Set("sort", string(sort))
+		if sort == domain.SortTop {
+			query.Set("t", string(period))
+		} else {
+			query.Del("t")
+		}
+		return r.URL.Path + "?" + query.Encode()
+	}
+	for _, sort := range domain.PostSorts {
+		sorts = append(sorts, SortLink{Label: sortLabels[sort], URL: link(sort, filter.Period), Active: sort == filter.Sort})
+	}
+	if filter.Sort == domain.SortTop {
+		for _, period := range domain.Periods {
+			periods = append(periods, SortLink{Label: periodLabels[period], URL: link(domain.SortTop, period), Active: period == filter.Period})
+		}
+	}
+	return sorts, periods
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"html/template"
+	"net/http"
+	"path/filepath"
+
+	"forum/forum/middleware"
+)
+
+// parseTemplates parses page templates together with the helpers every page
+// can use:
+//
+//	{{csrfField}}  the hidden CSRF token input, required in every POST form
+//	{{csrfToken}}  the bare token, for scripts that post JSON
+//	{{rendered .ContentHTML}}  the sanitized HTML of a post or comment; it
+//	               trusts its argument, so never pass it anything else
+func parseTemplates(r *http.Request, files ...string) (*template.Template, error) {
+	token := middleware.CSRFToken(r)
+	return template.New(filepath.Base(files[0])).Funcs(template.FuncMap{
+		"csrfField": func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + middleware.CSRFField + `" value="` + template.HTMLEscapeString(token) + `">`)
+		},
+		"csrfToken": func() string {
+			return token
+		},
+		"rendered": func(contentHTML string) template.HTML {
+			return template.HTML(contentHTML)
+		},
+	}).ParseFiles(files...)
+}
@@ -0,0 +1,7 @@
+package forum
+
+import "forum/forum/domain"
+
+type Mailer interface {
+	Send(mail domain.Mail) error
+}
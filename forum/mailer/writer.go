@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"forum/forum/domain"
+)
+
+// WriterMailer writes every message to an io.Writer instead of sending it.
+// It is meant for local development and tests.
+type WriterMailer struct {
+	from  string
+	w     io.Writer
+	mutex sync.Mutex
+}
+
+func NewWriterMailer(w io.Writer, from string) *WriterMailer {
+	return &WriterMailer{
+		from: from,
+		w:    w,
+	}
+}
+
+// NewFileMailer appends every message to the file at path.
+func NewFileMailer(path, from string) (*WriterMailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterMailer(f, from), nil
+}
+
+func (m *WriterMailer) Send(mail domain.Mail) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, err := m.w.Write(append(message(m.from, mail), "\r\n\r\n"...))
+	return err
+}
@@ -0,0 +1,124 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// languages maps the languages code blocks may be highlighted in, by the
+// name written after the opening fence, to the name of their lexer. Code in
+// other languages is shown as it is.
+var languages = map[string]string{
+	"go":         "go",
+	"golang":     "go",
+	"python":     "python",
+	"py":         "python",
+	"javascript": "javascript",
+	"js":         "javascript",
+	"typescript": "typescript",
+	"ts":         "typescript",
+	"java":       "java",
+	"c":          "c",
+	"cpp":        "c++",
+	"c++":        "c++",
+	"rust":       "rust",
+	"bash":       "bash",
+	"sh":         "bash",
+	"shell":      "bash",
+	"sql":        "sql",
+	"json":       "json",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+	"html":       "html",
+	"css":        "css",
+	"diff":       "diff",
+	"dockerfile": "docker",
+	"makefile":   "makefile",
+}
+
+// lineNumbersOption, written after the language as in ```go linenos, numbers
+// the lines of a code block.
+const lineNumbersOption = "linenos"
+
+// maxHighlightSize is the largest code block highlighted, in bytes; larger
+// ones are shown as they are so that no post is slow to render.
+const maxHighlightSize = 32 << 10
+
+// highlightStyle is the style the CSS classes of highlighted code follow;
+// static/highlight.css must be generated again with WriteStylesheet when it
+// changes.
+var highlightStyle = styles.Get("github")
+
+// WriteStylesheet writes the CSS giving highlighted code the colors of
+// highlightStyle.
+func WriteStylesheet(w io.Writer) error {
+	return chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(w, highlightStyle)
+}
+
+// codeBlockRenderer renders fenced code blocks, highlighting those in one
+// of the languages into spans with chroma's CSS classes.
+type codeBlockRenderer struct{}
+
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	var info []string
+	if n.Info != nil {
+		info = strings.Fields(string(n.Info.Segment.Value(source)))
+	}
+	var lexer chroma.Lexer
+	if len(info) > 0 && code.Len() <= maxHighlightSize {
+		if name, ok := languages[strings.ToLower(info[0])]; ok {
+			lexer = lexers.Get(name)
+		}
+	}
+	if lexer != nil {
+		iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+		if err == nil {
+			formatter := chromahtml.New(chromahtml.WithClasses(true), chromahtml.WithLineNumbers(hasOption(info[1:], lineNumbersOption)))
+			var highlighted bytes.Buffer
+			err = formatter.Format(&highlighted, highlightStyle, iterator)
+			if err == nil {
+				_, err = w.Write(highlighted.Bytes())
+				return ast.WalkSkipChildren, err
+			}
+		}
+	}
+
+	_, _ = w.WriteString("<pre><code>")
+	_, _ = w.WriteString(html.EscapeString(code.String()))
+	_, err := w.WriteString("</code></pre>\n")
+	return ast.WalkSkipChildren, err
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if strings.ToLower(o) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+// Package markdown renders the CommonMark text of posts and comments to
+// HTML that is safe to put in a page. Raw HTML in the text is dropped, and
+// the rendered HTML is cut down to an allowlist of elements and attributes:
+// links may only go to http, https and mailto addresses, or elsewhere on
+// the forum, and are marked rel="nofollow ugc". Fenced code blocks in a
+// known language, as in ```go, are highlighted with the CSS classes of
+// static/highlight.css.
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Version changes whenever Render may give different HTML for the same
+// text, so that renderings stored with an older version are redone.
+const Version = 2
+
+// linkRel is the rel of every link: written by users, not endorsed by the
+// forum.
+const linkRel = "nofollow ugc"
+
+var (
+	md = goldmark.New(
+		goldmark.WithParserOptions(parser.WithASTTransformers(util.Prioritized(linkTransformer{}, 100))),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(codeBlockRenderer{}, 100))),
+	)
+	policy = newPolicy()
+)
+
+// Render renders text to sanitized HTML.
+func Render(text string) string {
+	var buf bytes.Buffer
+	err := md.Convert([]byte(text), &buf)
+	if err != nil {
+		// Writing to a buffer does not fail, but the text is shown as it
+		// was written rather than lost if it ever did.
+		return "<p>" + html.EscapeString(text) + "</p>"
+	}
+	return policy.Sanitize(buf.String())
+}
+
+// newPolicy allows what CommonMark produces, less raw HTML, and the
+// classes of highlighted code.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "em", "strong", "code", "pre", "blockquote", "ul", "ol", "li",
+		"h1", "h2", "h3", "h4", "h5", "h6", "span")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-z0-9]+( [a-z0-9]+)*$`)).OnElements("pre", "code", "span")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("rel").Matching(regexp.MustCompile("^" + linkRel + "$")).OnElements("a")
+	p.AllowAttrs("src", "alt", "title").OnElements("img")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	return p
+}
+
+// linkTransformer marks the links of a text with linkRel.
+type linkTransformer struct{}
+
+func (linkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindLink, ast.KindAutoLink:
+			n.SetAttributeString("rel", []byte(linkRel))
+		}
+		return ast.WalkContinue, nil
+	})
+}
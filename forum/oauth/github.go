@@ -0,0 +1,91 @@
+package oauth
+
+import "strconv"
+
+const (
+	githubAuthURL   = "https://github.com/login/oauth/authorize"
+	githubTokenURL  = "https://github.com/login/oauth/access_token"
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+)
+
+// github is not an OpenID Connect provider: the profile comes from its REST
+// API, and the primary email with its verification state from /user/emails.
+type github struct {
+	endpoints
+	name        string
+	displayName string
+	icon        string
+}
+
+func newGitHub(config ProviderConfig) *github {
+	g := &github{
+		endpoints: endpoints{
+			clientID:     config.ClientID,
+			clientSecret: config.ClientSecret,
+			scopes:       config.Scopes,
+		},
+		name:        config.Name,
+		displayName: config.DisplayName,
+		icon:        config.Icon,
+	}
+	if g.scopes == nil {
+		g.scopes = []string{"read:user", "user:email"}
+	}
+	if g.displayName == "" {
+		g.displayName = "GitHub"
+	}
+	if g.icon == "" {
+		g.icon = "/static/Icons/git.svg"
+	}
+	return g
+}
+
+func (g *github) Name() string        { return g.name }
+func (g *github) DisplayName() string { return g.displayName }
+func (g *github) Icon() string        { return g.icon }
+
+func (g *github) AuthCodeURL(state, codeChallenge, redirectURI string) (string, error) {
+	return g.authCodeURL(githubAuthURL, state, codeChallenge, redirectURI)
+}
+
+func (g *github) Identity(code, codeVerifier, redirectURI string) (Identity, error) {
+	token, err := g.exchange(githubTokenURL, code, codeVerifier, redirectURI)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	err = getJSON(githubUserURL, token, &user)
+	if err != nil {
+		return Identity{}, err
+	}
+	if user.ID == 0 {
+		return Identity{}, ErrProfile
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	err = getJSON(githubEmailsURL, token, &emails)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	identity := Identity{
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Username: user.Login,
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+		}
+	}
+	return identity, nil
+}
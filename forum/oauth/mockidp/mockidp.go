@@ -0,0 +1,243 @@
+// Package mockidp is a minimal OpenID Connect provider for development and
+// testing. It signs in whoever fills in its form, so it must never be exposed
+// in production. Everything is kept in memory.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const codeTTL = time.Minute
+
+// Server implements the discovery, authorization, token and userinfo
+// endpoints. Mount it with http.StripPrefix so that Issuer is its root.
+type Server struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	tokens map[string]claims
+}
+
+type claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type grant struct {
+	claims        claims
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	expires       time.Time
+}
+
+func New(issuer, clientID, clientSecret string) *Server {
+	return &Server{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		codes:        map[string]grant{},
+		tokens:       map[string]claims{},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		s.handleDiscovery(w, r)
+	case "/authorize":
+		s.handleAuthorize(w, r)
+	case "/token":
+		s.handleToken(w, r)
+	case "/userinfo":
+		s.handleUserinfo(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                           s.Issuer,
+		"authorization_endpoint":           s.Issuer + "/authorize",
+		"token_endpoint":                   s.Issuer + "/token",
+		"userinfo_endpoint":                s.Issuer + "/userinfo",
+		"response_types_supported":         []string{"code"},
+		"subject_types_supported":          []string{"public"},
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>Mock identity provider</title></head>
+<body>
+<h1>Mock identity provider</h1>
+<p>Sign in as any user. This provider is for development only.</p>
+<form method="POST">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}
+<p><label>Email <input type="email" name="email" required></label></p>
+<p><label>Username <input type="text" name="username"></label></p>
+<p><label><input type="checkbox" name="email_verified" value="1" checked> Email verified</label></p>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+// handleAuthorize shows a sign-in form on GET and issues a code on POST.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for _, key := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"} {
+		params[key] = r.Form.Get(key)
+	}
+	if params["response_type"] != "code" || params["client_id"] != s.ClientID {
+		http.Error(w, "unsupported response type or unknown client", http.StatusBadRequest)
+		return
+	}
+	if params["code_challenge_method"] != "S256" || params["code_challenge"] == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(params["redirect_uri"])
+	if err != nil || !redirect.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		authorizePage.Execute(w, struct{ Params map[string]string }{params})
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+	subject := sha256.Sum256([]byte(strings.ToLower(email)))
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = grant{
+		claims: claims{
+			Subject:           hex.EncodeToString(subject[:8]),
+			Email:             email,
+			EmailVerified:     r.PostFormValue("email_verified") != "",
+			PreferredUsername: strings.TrimSpace(r.PostFormValue("username")),
+		},
+		clientID:      params["client_id"],
+		redirectURI:   params["redirect_uri"],
+		codeChallenge: params["code_challenge"],
+		expires:       time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	q := redirect.Query()
+	q.Set("code", code)
+	q.Set("state", params["state"])
+	redirect.RawQuery = q.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID != s.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.ClientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	s.mu.Lock()
+	code := r.PostFormValue("code")
+	g, found := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(verifier[:])
+	if !found || time.Now().After(g.expires) || g.clientID != clientID ||
+		g.redirectURI != r.PostFormValue("redirect_uri") || challenge != g.codeChallenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	token := randomString()
+	s.mu.Lock()
+	s.tokens[token] = g.claims
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (s *Server) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	c, ok := s.tokens[token]
+	s.mu.Unlock()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,236 @@
+// Package oauth signs users in with external identity providers using the
+// OAuth 2.0 authorization code flow with PKCE. Providers are described by
+// configuration; GitHub and OpenID Connect (including Google) are supported.
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrExchange = errors.New("oauth: could not exchange the authorization code")
+	ErrProfile  = errors.New("oauth: could not read the user profile")
+)
+
+// Identity is what a provider tells us about the signed in user.
+type Identity struct {
+	// Subject is the provider's stable, unique id for the user.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Username is a suggestion for the local username of new accounts.
+	Username string
+}
+
+// Provider is an identity provider the forum can redirect users to.
+type Provider interface {
+	Name() string
+	DisplayName() string
+	// Icon is the path of an image shown on the sign-in button, or empty.
+	Icon() string
+	// AuthCodeURL is where the user is sent to sign in.
+	AuthCodeURL(state, codeChallenge, redirectURI string) (string, error)
+	// Identity redeems the authorization code returned to redirectURI and
+	// fetches the user's profile.
+	Identity(code, codeVerifier, redirectURI string) (Identity, error)
+}
+
+// ProviderConfig is one entry of the providers file.
+type ProviderConfig struct {
+	// Name identifies the provider in URLs, e.g. /auth/github.
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	DisplayName string `json:"display_name"`
+	Icon        string `json:"icon"`
+	// Issuer is the OpenID Connect issuer URL, used to discover the
+	// endpoints of "oidc" providers.
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// LoadProviders reads a JSON array of ProviderConfig from path.
+func LoadProviders(path string) ([]Provider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []ProviderConfig
+	err = json.Unmarshal(data, &configs)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: %s: %w", path, err)
+	}
+
+	var providers []Provider
+	seen := map[string]bool{}
+	for _, config := range configs {
+		provider, err := NewProvider(config)
+		if err != nil {
+			return nil, err
+		}
+		if seen[provider.Name()] {
+			return nil, fmt.Errorf("oauth: duplicate provider %q", provider.Name())
+		}
+		seen[provider.Name()] = true
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// NewProvider builds a provider from its configuration. The "google" type is
+// an OpenID Connect provider with Google's issuer filled in.
+func NewProvider(config ProviderConfig) (Provider, error) {
+	if config.Name == "" || config.ClientID == "" {
+		return nil, errors.New("oauth: provider needs a name and a client_id")
+	}
+	if strings.ContainsAny(config.Name, "/?#") {
+		return nil, fmt.Errorf("oauth: invalid provider name %q", config.Name)
+	}
+
+	switch config.Type {
+	case "github":
+		return newGitHub(config), nil
+	case "google":
+		if config.Issuer == "" {
+			config.Issuer = "https://accounts.google.com"
+		}
+		if config.DisplayName == "" {
+			config.DisplayName = "Google"
+		}
+		if config.Icon == "" {
+			config.Icon = "/static/Icons/google.svg"
+		}
+		return newOIDC(config), nil
+	case "oidc":
+		if config.Issuer == "" {
+			return nil, fmt.Errorf("oauth: provider %q needs an issuer", config.Name)
+		}
+		return newOIDC(config), nil
+	}
+	return nil, fmt.Errorf("oauth: provider %q has unknown type %q", config.Name, config.Type)
+}
+
+// NewVerifier returns a PKCE code verifier and its S256 challenge.
+func NewVerifier() (verifier, challenge string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(b)
+	return verifier, S256(verifier), nil
+}
+
+// S256 is the PKCE transformation of a code verifier.
+func S256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// endpoints are the URLs and credentials of the authorization code flow,
+// shared by all provider types.
+type endpoints struct {
+	clientID     string
+	clientSecret string
+	scopes       []string
+}
+
+func (e endpoints) authCodeURL(authURL, state, codeChallenge, redirectURI string) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", e.clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(e.scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// exchange redeems an authorization code for an access token.
+func (e endpoints) exchange(tokenURL, code, codeVerifier, redirectURI string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+		"client_id":     {e.clientID},
+	}
+	if e.clientSecret != "" {
+		form.Set("client_secret", e.clientSecret)
+	}
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		Error       string `json:"error"`
+	}
+	err = doJSON(req, &token)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrExchange, err)
+	}
+	if token.Error != "" || token.AccessToken == "" {
+		return "", fmt.Errorf("%w: %s", ErrExchange, token.Error)
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", fmt.Errorf("%w: unsupported token type %s", ErrExchange, token.TokenType)
+	}
+	return token.AccessToken, nil
+}
+
+// getJSON fetches an API resource on behalf of the user.
+func getJSON(url, accessToken string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	err = doJSON(req, v)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrProfile, err)
+	}
+	return nil
+}
+
+func doJSON(req *http.Request, v any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return json.Unmarshal(body, v)
+}
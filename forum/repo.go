@@ -1,36 +1,125 @@
 package forum
 
-import "forum/forum/domain"
+import (
+	"time"
+
+	"forum/forum/domain"
+)
 
 type Repo interface {
 	GetUser(username string) (domain.User, error)
 	SaveSession(session domain.Session) error
 	GetSession(sessionID string) (domain.Session, error)
+	RenewSession(sessionID string, lastSeen, expiration time.Time) error
+	GetUserSessions(userID int) ([]domain.Session, error)
+	DeleteUserSession(userID, id int) error
+	InvalidateOtherSessions(userID int, sessionID string) error
+	DeleteSession(sessionID string) error
+	DeleteExpiredSessions(now time.Time) (int64, error)
 	SaveUser(domain.User) error
-	GetCommentsByUser(userID int) ([]domain.Comments, error)
-	GetCreatedPosts(userID int) ([]domain.Posts, error)
+	GetCommentsByUser(userID int, req domain.PageRequest) ([]domain.Comments, domain.Page, error)
 	SavePosts(domain.Posts) error
-	GetPosts() ([]domain.Posts, error)
-	GetUserPosts(userId int) ([]domain.Posts, error)
-	DeletePost(postId int) error
-	DeleteComment(comment_id int) error
+	GetPosts(filter domain.PostFilter, viewerID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetUserPosts(userId int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	DeletePost(postId int, entry *domain.AuditEntry) error
+	DeleteComment(comment_id int, entry *domain.AuditEntry) error
 	GetPostByID(postID int) (domain.Posts, error)
 	AddComment(domain.Comments) error
-	GetComments(postId int) ([]domain.Comments, error)
+	GetComments(postId int, viewerID int) ([]domain.Comments, error)
+	GetCommentByID(commentID int) (domain.Comments, error)
 	GetUserById(userId int) ([]domain.User, error)
 	LikePost(postID, userID int, notification domain.Notification) error
 	DislikePost(postID, userID int, notification domain.Notification) error
-	GetLikedPostIDs(userID int) ([]int, error)
-	GetDislikedPostIDs(userID int) ([]int, error)
-	GetPostsByCategories(categories []string) ([]domain.Posts, error)
+	GetLikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetDislikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetPostsByCategories(filter domain.PostFilter, viewerID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	GetCategories() ([]domain.Category, error)
+	GetCategory(id int) (domain.Category, error)
+	SaveCategory(c domain.Category, entry domain.AuditEntry) (int, error)
+	UpdateCategory(c domain.Category, entry domain.AuditEntry) error
+	SetCategoryOrder(ids []int, entry domain.AuditEntry) error
+	MergeCategories(fromID, intoID int, entry domain.AuditEntry) error
+	GetTag(name string) (domain.Tag, error)
+	GetTags(viewerID int) ([]domain.Tag, error)
+	SearchTags(prefix string, limit int) ([]string, error)
+	GetPostsByTag(tagID int, viewerID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error)
+	SetTagBanned(tagID int, banned bool, entry domain.AuditEntry) error
+	RenameTag(tagID int, name string, entry domain.AuditEntry) error
+	MergeTags(fromID, intoID int, entry domain.AuditEntry) error
 	GetUserByEmail(email string) (domain.User, error)
 	LikeComment(commentID int, userID int, notification_comments domain.Notification_comments) error
 	DislikeComment(commentID int, userID int, notification_comments domain.Notification_comments) error
-	InvalidateSessions(userID int) error
+	InvalidateSessions(userID int, entry *domain.AuditEntry) error
 	CreateNotification(notification domain.Notification) error
 	CreateNotificationComments(notification domain.Notification_comments) error
-	GetAllNotificationsComment(ownerID int) ([]domain.Notification_comments, error)
-	GetAllNotifications(ownerID int) ([]domain.Notification, error)
-	EditPost(postId int, post domain.Posts) error
-	EditComment(commentId int, comment domain.Comments) error
+	GetAllNotificationsComment(ownerID int, req domain.PageRequest) ([]domain.Notification_comments, domain.Page, error)
+	GetAllNotifications(ownerID int, req domain.PageRequest) ([]domain.Notification, domain.Page, error)
+	EditPost(postId int, post domain.Posts, entry *domain.AuditEntry) error
+	EditComment(commentId int, comment domain.Comments, entry *domain.AuditEntry) error
+	UpdatePassword(userID int, password string, entry *domain.AuditEntry) error
+	SavePasswordReset(reset domain.PasswordReset) error
+	GetPasswordReset(tokenHash string) (domain.PasswordReset, error)
+	ConsumePasswordReset(id int) error
+	DeletePasswordResets(userID int) error
+	SetEmailVerified(userID int) error
+	SaveEmailVerification(verification domain.EmailVerification) error
+	GetEmailVerification(tokenHash string) (domain.EmailVerification, error)
+	DeleteEmailVerifications(userID int) error
+	SetUserRole(userID int, role domain.Role, entry domain.AuditEntry) error
+	GetTwoFactor(userID int) (domain.TwoFactor, error)
+	SaveTwoFactor(tf domain.TwoFactor) error
+	UseTwoFactorCounter(userID int, counter int64) error
+	DeleteTwoFactor(userID int, entry *domain.AuditEntry) error
+	SaveRecoveryCodes(userID int, codeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) error
+	CountRecoveryCodes(userID int) (int, error)
+	SaveLoginChallenge(challenge domain.LoginChallenge) error
+	GetLoginChallenge(tokenHash string) (domain.LoginChallenge, error)
+	CountLoginChallengeAttempt(id int) error
+	DeleteLoginChallenge(id int) error
+	SavePasskey(passkey domain.Passkey) error
+	GetPasskeys(userID int) ([]domain.Passkey, error)
+	GetPasskeyByCredentialID(credentialID []byte) (domain.Passkey, error)
+	UpdatePasskeyUsage(id int, signCount uint32, lastUsed time.Time) error
+	RenamePasskey(userID, id int, name string) error
+	DeletePasskey(userID, id int) error
+	SaveWebAuthnChallenge(challenge domain.WebAuthnChallenge) error
+	ConsumeWebAuthnChallenge(challengeHash, typ string, now time.Time) (domain.WebAuthnChallenge, error)
+	SaveUserIdentity(identity domain.UserIdentity) error
+	GetUserIdentity(provider, subject string) (domain.UserIdentity, error)
+	GetUserIdentities(userID int) ([]domain.UserIdentity, error)
+	DeleteUserIdentity(userID, id int) error
+	SaveOAuthState(state domain.OAuthState) error
+	ConsumeOAuthState(stateHash, provider string, now time.Time) (domain.OAuthState, error)
+	GetThrottle(key string) (domain.Throttle, error)
+	CountThrottleEvent(key string, now, resetBefore time.Time) (int, error)
+	BlockThrottle(key string, until time.Time) error
+	DeleteThrottle(key string) error
+	DeleteStaleThrottles(before time.Time) (int64, error)
+	SaveReport(report domain.Report) error
+	GetOpenReports() ([]domain.Report, error)
+	GetOpenReportsFor(postID, commentID int) ([]domain.Report, error)
+	ResolveReports(postID, commentID int, resolution domain.ModerationAction, moderatorID int, now time.Time, entry *domain.AuditEntry) (int64, error)
+	SetPostHidden(postID int, hidden bool, entry domain.AuditEntry) error
+	SetCommentHidden(commentID int, hidden bool, entry domain.AuditEntry) error
+	SearchUsers(query string, limit int) ([]domain.UserSummary, error)
+	SetUserSuspension(userID int, until time.Time, entry domain.AuditEntry) error
+	SetUserBan(userID int, banned bool, reason string, entry domain.AuditEntry) error
+	SetUserShadowBan(userID int, shadowBanned bool, entry domain.AuditEntry) error
+	GetAuditLog(filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	SearchPosts(filter domain.SearchFilter, viewerID int, req domain.PageRequest) ([]domain.SearchResult, domain.Page, error)
+	SearchComments(filter domain.SearchFilter, viewerID int, req domain.PageRequest) ([]domain.SearchResult, domain.Page, error)
+	RebuildSearchIndex() error
+	SaveSavedSearch(search domain.SavedSearch) error
+	CountSavedSearches(userID int) (int, error)
+	GetSavedSearches(userID int) ([]domain.SavedSearch, error)
+	GetAlertingSearches() ([]domain.SavedSearch, error)
+	UpdateSavedSearch(userID, id int, notify, digest bool) error
+	DeleteSavedSearch(userID, id int) error
+	LatestPostID() (int, error)
+	MatchNewPosts(filter domain.SearchFilter, ownerID, afterID, upToID int) ([]int, error)
+	SaveSearchAlerts(search domain.SavedSearch, postIDs []int, upToID int, now time.Time) error
+	GetSearchAlerts(ownerID int, req domain.PageRequest) ([]domain.SearchAlert, domain.Page, error)
+	GetPendingAlerts() ([]domain.SearchAlert, error)
+	ClearPendingAlerts(ids []int) error
 }
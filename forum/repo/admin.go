@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"forum/forum/domain"
+)
+
+// SearchUsers returns up to limit users whose name or email contains query,
+// newest first, with the number of posts and comments they wrote.
+func (r *RepoSqlLite) SearchUsers(query string, limit int) ([]domain.UserSummary, error) {
+	pattern := "%" + escapeLike(query) + "%"
+	rows, err := r.db.Query(`
+		SELECT `+userColumns+`,
+			(SELECT COUNT(*) FROM posts p WHERE p.user_id = u.user_id),
+			(SELECT COUNT(*) FROM comments c WHERE c.user_id = u.user_id)
+		FROM users u
+		WHERE u.username LIKE ? ESCAPE '\' OR u.email LIKE ? ESCAPE '\'
+		ORDER BY u.registration_date DESC, u.user_id DESC
+		LIMIT ?`, pattern, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []domain.UserSummary
+	for rows.Next() {
+		var u domain.UserSummary
+		var registration, suspendedUntil sql.NullTime
+		err = rows.Scan(&u.UserId, &u.Username, &u.Email, &u.Password, &u.EmailVerified, &u.Role, &registration, &suspendedUntil, &u.Banned, &u.BanReason, &u.ShadowBanned,
+			&u.PostCount, &u.CommentCount)
+		if err != nil {
+			return nil, err
+		}
+		u.Password = ""
+		u.RegistrationDate = registration.Time
+		u.SuspendedUntil = suspendedUntil.Time
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func escapeLike(s string) string {
+	var escaped []rune
+	for _, c := range s {
+		if c == '%' || c == '_' || c == '\\' {
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, c)
+	}
+	return string(escaped)
+}
+
+// SetUserSuspension suspends a user until the given time; the zero time
+// lifts the suspension.
+func (r *RepoSqlLite) SetUserSuspension(userID int, until time.Time, entry domain.AuditEntry) error {
+	var value sql.NullTime
+	if !until.IsZero() {
+		value = sql.NullTime{Time: until, Valid: true}
+	}
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE users SET suspended_until = ? WHERE user_id = ?", value, userID)
+		return err
+	})
+}
+
+func (r *RepoSqlLite) SetUserShadowBan(userID int, shadowBanned bool, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE users SET shadow_banned = ? WHERE user_id = ?", shadowBanned, userID)
+		return err
+	})
+}
+
+func (r *RepoSqlLite) SetUserBan(userID int, banned bool, reason string, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE users SET banned = ?, ban_reason = ? WHERE user_id = ?", banned, reason, userID)
+		return err
+	})
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"database/sql"
+	"strings"
+
+	"forum/forum/domain"
+)
+
+// execer is a database or a transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// audited makes change in a transaction that also writes entry, unless it
+// is nil, so that the audit log records a privileged change exactly when it
+// is made.
+func (r *RepoSqlLite) audited(entry *domain.AuditEntry, change func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = change(tx)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		err = saveAuditEntry(tx, *entry)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// saveAuditEntry appends an entry to the audit log. The table has triggers
+// refusing updates and deletes, so entries cannot be changed afterwards.
+func saveAuditEntry(db execer, entry domain.AuditEntry) error {
+	_, err := db.Exec("INSERT INTO audit_log (actor_id, actor_name, action, target_type, target_id, before, reason, creation_date) VALUES (?,?,?,?,?,?,?,?)",
+		entry.ActorId, entry.ActorName, entry.Action, entry.TargetType, entry.TargetId, entry.Before, entry.Reason, entry.CreationDate)
+	return err
+}
+
+// GetAuditLog returns the entries matching filter, newest first.
+func (r *RepoSqlLite) GetAuditLog(filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	if filter.ActorName != "" {
+		conditions = append(conditions, "actor_name = ?")
+		args = append(args, filter.ActorName)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetId != 0 {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetId)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "creation_date >= ?")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "creation_date < ?")
+		args = append(args, filter.Until)
+	}
+
+	query := "SELECT id, actor_id, actor_name, action, target_type, target_id, before, reason, creation_date FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY creation_date DESC, id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.AuditEntry
+	for rows.Next() {
+		var e domain.AuditEntry
+		err = rows.Scan(&e.Id, &e.ActorId, &e.ActorName, &e.Action, &e.TargetType, &e.TargetId, &e.Before, &e.Reason, &e.CreationDate)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
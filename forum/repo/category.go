@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"forum/forum/domain"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// defaultCategories fill the categories table of a new forum.
+var defaultCategories = []domain.Category{
+	{Slug: "comedy", Name: "Comedy", Description: "Sitcoms, stand-up and anything that makes you laugh", Position: 1},
+	{Slug: "drama", Name: "Drama", Description: "Stories that take themselves seriously", Position: 2},
+	{Slug: "horror", Name: "Horror", Description: "Things that go bump in the night", Position: 3},
+	{Slug: "other", Name: "Other", Description: "Everything else", Position: 4},
+}
+
+// migrateCategories gives categories from older databases a slug, creates
+// the default categories in an empty table and files posts written before
+// post_categories existed under the category named in their category column,
+// or under "other" when there is no such category.
+func (r *RepoSqlLite) migrateCategories() error {
+	_, err := r.db.Exec("UPDATE categories SET slug = lower(replace(trim(name), ' ', '-')) || '-' || category_id WHERE slug = ''")
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS categories_slug ON categories (slug)")
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = r.db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		for _, c := range defaultCategories {
+			_, err = r.db.Exec("INSERT INTO categories (slug, name, description, position) VALUES (?,?,?,?)", c.Slug, c.Name, c.Description, c.Position)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO post_categories (post_id, category_id)
+		SELECT post_id, category_id FROM (
+			SELECT p.post_id, COALESCE(
+				(SELECT c.category_id FROM categories c WHERE c.name = p.category),
+				(SELECT c.category_id FROM categories c WHERE c.slug = 'other')) AS category_id
+			FROM posts p
+			WHERE NOT EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = p.post_id)
+		) WHERE category_id IS NOT NULL`)
+	return err
+}
+
+const categoryColumns = "c.category_id, c.slug, c.name, c.description, c.position, c.archived, c.post_policy, c.allow_comments, c.read_only"
+
+func scanCategory(row rowScanner, dest ...any) (domain.Category, error) {
+	var c domain.Category
+	err := row.Scan(append(dest, &c.Id, &c.Slug, &c.Name, &c.Description, &c.Position, &c.Archived, &c.PostPolicy, &c.AllowComments, &c.ReadOnly)...)
+	return c, err
+}
+
+// GetCategories returns every category, archived ones included, in display
+// order.
+func (r *RepoSqlLite) GetCategories() ([]domain.Category, error) {
+	rows, err := r.db.Query("SELECT " + categoryColumns + " FROM categories c ORDER BY c.position, c.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []domain.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *RepoSqlLite) GetCategory(id int) (domain.Category, error) {
+	c, err := scanCategory(r.db.QueryRow("SELECT "+categoryColumns+" FROM categories c WHERE c.category_id = ?", id))
+	if err == sql.ErrNoRows {
+		return domain.Category{}, domain.ErrCategoryNotFound
+	}
+	return c, err
+}
+
+// SaveCategory adds a category at the end of the list and returns its id.
+// entry, the audit log entry of its creation, is written along with it and
+// given that id.
+func (r *RepoSqlLite) SaveCategory(c domain.Category, entry domain.AuditEntry) (int, error) {
+	err := r.audited(&entry, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			INSERT INTO categories (slug, name, description, position, archived, post_policy, allow_comments, read_only)
+			SELECT ?, ?, ?, COALESCE(MAX(position), 0) + 1, ?, ?, ?, ? FROM categories`,
+			c.Slug, c.Name, c.Description, c.Archived, c.PostPolicy, c.AllowComments, c.ReadOnly)
+		if isUniqueViolation(err) {
+			return domain.ErrCategoryExists
+		}
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		entry.TargetId = int(id)
+		return err
+	})
+	return entry.TargetId, err
+}
+
+// UpdateCategory saves everything about a category but its position.
+func (r *RepoSqlLite) UpdateCategory(c domain.Category, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE categories SET slug = ?, name = ?, description = ?, archived = ?, post_policy = ?, allow_comments = ?, read_only = ? WHERE category_id = ?",
+			c.Slug, c.Name, c.Description, c.Archived, c.PostPolicy, c.AllowComments, c.ReadOnly, c.Id)
+		if isUniqueViolation(err) {
+			return domain.ErrCategoryExists
+		}
+		return err
+	})
+}
+
+// SetCategoryOrder numbers the categories in the order of ids.
+func (r *RepoSqlLite) SetCategoryOrder(ids []int, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		for i, id := range ids {
+			_, err := tx.Exec("UPDATE categories SET position = ? WHERE category_id = ?", i+1, id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MergeCategories files the posts of the category fromID under intoID and
+// deletes fromID.
+func (r *RepoSqlLite) MergeCategories(fromID, intoID int, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT OR IGNORE INTO post_categories (post_id, category_id) SELECT post_id, ? FROM post_categories WHERE category_id = ?", intoID, fromID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM post_categories WHERE category_id = ?", fromID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE posts SET category_id = ?, category = (SELECT name FROM categories WHERE category_id = ?) WHERE category_id = ?", intoID, intoID, fromID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM categories WHERE category_id = ?", fromID)
+		return err
+	})
+}
+
+// attachCategories fills in the categories of each post.
+func (r *RepoSqlLite) attachCategories(posts []domain.Posts) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	index := make(map[int][]int)
+	args := make([]any, 0, len(posts))
+	for i, p := range posts {
+		if _, ok := index[p.PostId]; !ok {
+			args = append(args, p.PostId)
+		}
+		index[p.PostId] = append(index[p.PostId], i)
+	}
+
+	rows, err := r.db.Query("SELECT pc.post_id, "+categoryColumns+` FROM post_categories pc
+		JOIN categories c ON c.category_id = pc.category_id
+		WHERE pc.post_id IN (`+placeholders(len(args))+`)
+		ORDER BY c.position, c.name`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		c, err := scanCategory(rows, &postID)
+		if err != nil {
+			return err
+		}
+		for _, i := range index[postID] {
+			posts[i].Categories = append(posts[i].Categories, c)
+		}
+	}
+	return rows.Err()
+}
+
+// setPostCategories replaces the categories of a post.
+func setPostCategories(tx *sql.Tx, postID int, categories []domain.Category) error {
+	_, err := tx.Exec("DELETE FROM post_categories WHERE post_id = ?", postID)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		_, err = tx.Exec("INSERT OR IGNORE INTO post_categories (post_id, category_id) VALUES (?,?)", postID, c.Id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPostsByCategories returns a page of the posts the viewer may see that
+// are in any of the categories of filter, or in all of them with
+// filter.MatchAll.
+func (r *RepoSqlLite) GetPostsByCategories(filter domain.PostFilter, viewerID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	if len(filter.Categories) == 0 {
+		return nil, domain.Page{}, nil
+	}
+	needed := 1
+	if filter.MatchAll {
+		needed = len(filter.Categories)
+	}
+	args := make([]any, 0, len(filter.Categories)+2)
+	args = append(args, viewerID)
+	for _, slug := range filter.Categories {
+		args = append(args, slug)
+	}
+	args = append(args, needed)
+
+	return r.sortedPostPage(visibleTo+` AND post_id IN (
+		SELECT pc.post_id FROM post_categories pc
+		JOIN categories c ON c.category_id = pc.category_id
+		WHERE c.slug IN (`+placeholders(len(filter.Categories))+`)
+		GROUP BY pc.post_id
+		HAVING COUNT(DISTINCT c.category_id) >= ?)`, filter, req, args...)
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/forum/domain"
+)
+
+func (r *RepoSqlLite) SaveUserIdentity(identity domain.UserIdentity) error {
+	_, err := r.db.Exec("INSERT INTO user_identities (user_id, provider, subject, email, creation_date) VALUES (?,?,?,?,?)", identity.UserId, identity.Provider, identity.Subject, identity.Email, identity.CreationDate)
+	return err
+}
+
+func (r *RepoSqlLite) GetUserIdentity(provider, subject string) (domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	err := r.db.QueryRow("SELECT id, user_id, provider, subject, email, creation_date FROM user_identities WHERE provider = ? AND subject = ?", provider, subject).
+		Scan(&identity.Id, &identity.UserId, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.UserIdentity{}, domain.ErrIdentityNotFound
+	}
+	return identity, err
+}
+
+func (r *RepoSqlLite) GetUserIdentities(userID int) ([]domain.UserIdentity, error) {
+	rows, err := r.db.Query("SELECT id, user_id, provider, subject, email, creation_date FROM user_identities WHERE user_id = ? ORDER BY creation_date", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []domain.UserIdentity
+	for rows.Next() {
+		var identity domain.UserIdentity
+		err = rows.Scan(&identity.Id, &identity.UserId, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreationDate)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (r *RepoSqlLite) DeleteUserIdentity(userID, id int) error {
+	res, err := r.db.Exec("DELETE FROM user_identities WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (r *RepoSqlLite) SaveOAuthState(state domain.OAuthState) error {
+	_, err := r.db.Exec("INSERT INTO oauth_states (state_hash, provider, code_verifier, user_id, expiration_date) VALUES (?,?,?,?,?)", state.StateHash, state.Provider, state.CodeVerifier, state.UserId, state.ExpirationDate)
+	return err
+}
+
+// ConsumeOAuthState looks up and deletes a state so that a callback can only
+// be handled once. Expired states are removed on the way.
+func (r *RepoSqlLite) ConsumeOAuthState(stateHash, provider string, now time.Time) (domain.OAuthState, error) {
+	_, err := r.db.Exec("DELETE FROM oauth_states WHERE julianday(expiration_date) < julianday(?)", now)
+	if err != nil {
+		return domain.OAuthState{}, err
+	}
+
+	var state domain.OAuthState
+	err = r.db.QueryRow("SELECT id, state_hash, provider, code_verifier, user_id, expiration_date FROM oauth_states WHERE state_hash = ? AND provider = ?", stateHash, provider).
+		Scan(&state.Id, &state.StateHash, &state.Provider, &state.CodeVerifier, &state.UserId, &state.ExpirationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.OAuthState{}, domain.ErrOAuthFailed
+	}
+	if err != nil {
+		return domain.OAuthState{}, err
+	}
+
+	res, err := r.db.Exec("DELETE FROM oauth_states WHERE id = ?", state.Id)
+	if err != nil {
+		return domain.OAuthState{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return domain.OAuthState{}, err
+	}
+	if n == 0 {
+		return domain.OAuthState{}, domain.ErrOAuthFailed
+	}
+	return state, nil
+}
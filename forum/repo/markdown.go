@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"log"
+
+	"forum/forum/markdown"
+)
+
+// renderStaleContent renders the posts and comments whose stored HTML was
+// made by another version of the markdown package, or by none for those
+// written before Markdown was supported.
+func (r *RepoSqlLite) renderStaleContent() error {
+	for _, table := range []struct{ name, id string }{{"posts", "post_id"}, {"comments", "comment_id"}} {
+		n, err := r.renderStale(table.name, table.id)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("Rendered the Markdown of %d %s", n, table.name)
+		}
+	}
+	return nil
+}
+
+func (r *RepoSqlLite) renderStale(table, idColumn string) (int, error) {
+	rows, err := r.db.Query("SELECT "+idColumn+", content FROM "+table+" WHERE content_version != ?", markdown.Version)
+	if err != nil {
+		return 0, err
+	}
+	type source struct {
+		id      int
+		content string
+	}
+	var stale []source
+	for rows.Next() {
+		var s source
+		err = rows.Scan(&s.id, &s.content)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stale = append(stale, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, s := range stale {
+		_, err = tx.Exec("UPDATE "+table+" SET content_html = ?, content_version = ? WHERE "+idColumn+" = ?", markdown.Render(s.content), markdown.Version, s.id)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), tx.Commit()
+}
@@ -0,0 +1,64 @@
+package repo
+
+import "forum/forum/domain"
+
+// pageClause returns what to append to a query, after its WHERE conditions,
+// to select the page req asks for from a list ordered newest first by
+// column. It takes one item more than fits on the page, so that pageOf can
+// tell whether there is a page beyond.
+func pageClause(column string, req domain.PageRequest) (string, []any) {
+	switch {
+	case req.Cursor.IsZero():
+		return " ORDER BY " + column + " DESC LIMIT ?", []any{req.Limit() + 1}
+	case req.Backward:
+		return " AND " + column + " > ? ORDER BY " + column + " ASC LIMIT ?", []any{req.Cursor.Id, req.Limit() + 1}
+	default:
+		return " AND " + column + " < ? ORDER BY " + column + " DESC LIMIT ?", []any{req.Cursor.Id, req.Limit() + 1}
+	}
+}
+
+// keyedPageClause is pageClause for a list ordered highest first by key,
+// and newest first by column among items with the same key.
+func keyedPageClause(key, column string, req domain.PageRequest) (string, []any) {
+	switch {
+	case req.Cursor.IsZero():
+		return " ORDER BY " + key + " DESC, " + column + " DESC LIMIT ?", []any{req.Limit() + 1}
+	case req.Backward:
+		return " AND (" + key + " > ? OR " + key + " = ? AND " + column + " > ?) ORDER BY " + key + " ASC, " + column + " ASC LIMIT ?",
+			[]any{req.Cursor.Key, req.Cursor.Key, req.Cursor.Id, req.Limit() + 1}
+	default:
+		return " AND (" + key + " < ? OR " + key + " = ? AND " + column + " < ?) ORDER BY " + key + " DESC, " + column + " DESC LIMIT ?",
+			[]any{req.Cursor.Key, req.Cursor.Key, req.Cursor.Id, req.Limit() + 1}
+	}
+}
+
+// pageOf turns the rows of a query ending in pageClause or keyedPageClause
+// into the page, in list order, and the cursors of the pages around it.
+func pageOf[T any](items []T, req domain.PageRequest, cursor func(T) domain.Cursor) ([]T, domain.Page) {
+	var page domain.Page
+	backward := req.Backward && !req.Cursor.IsZero()
+	more := len(items) > req.Limit()
+	if more {
+		items = items[:req.Limit()]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if len(items) == 0 {
+		return items, page
+	}
+
+	// Paging backward started from the page after this one, and paging
+	// forward from a cursor from the page before it.
+	if more && !backward || backward {
+		next := cursor(items[len(items)-1])
+		page.Next = &next
+	}
+	if more && backward || !backward && !req.Cursor.IsZero() {
+		prev := cursor(items[0])
+		page.Prev = &prev
+	}
+	return items, page
+}
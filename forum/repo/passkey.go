@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/forum/domain"
+)
+
+const passkeyColumns = "id, user_id, credential_id, public_key, sign_count, name, creation_date, last_used"
+
+func scanPasskey(row rowScanner) (domain.Passkey, error) {
+	var passkey domain.Passkey
+	var lastUsed sql.NullTime
+	err := row.Scan(&passkey.Id, &passkey.UserId, &passkey.CredentialId, &passkey.PublicKey, &passkey.SignCount, &passkey.Name, &passkey.CreationDate, &lastUsed)
+	if err != nil {
+		return domain.Passkey{}, err
+	}
+	passkey.LastUsed = lastUsed.Time
+	return passkey, nil
+}
+
+func (r *RepoSqlLite) SavePasskey(passkey domain.Passkey) error {
+	_, err := r.db.Exec("INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, name, creation_date) VALUES (?,?,?,?,?,?)", passkey.UserId, passkey.CredentialId, passkey.PublicKey, passkey.SignCount, passkey.Name, passkey.CreationDate)
+	return err
+}
+
+func (r *RepoSqlLite) GetPasskeys(userID int) ([]domain.Passkey, error) {
+	rows, err := r.db.Query("SELECT "+passkeyColumns+" FROM webauthn_credentials WHERE user_id = ? ORDER BY creation_date", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var passkeys []domain.Passkey
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, passkey)
+	}
+	return passkeys, rows.Err()
+}
+
+func (r *RepoSqlLite) GetPasskeyByCredentialID(credentialID []byte) (domain.Passkey, error) {
+	passkey, err := scanPasskey(r.db.QueryRow("SELECT "+passkeyColumns+" FROM webauthn_credentials WHERE credential_id = ?", credentialID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Passkey{}, domain.ErrPasskeyNotFound
+	}
+	return passkey, err
+}
+
+func (r *RepoSqlLite) UpdatePasskeyUsage(id int, signCount uint32, lastUsed time.Time) error {
+	_, err := r.db.Exec("UPDATE webauthn_credentials SET sign_count = ?, last_used = ? WHERE id = ?", signCount, lastUsed, id)
+	return err
+}
+
+// RenamePasskey and DeletePasskey only touch credentials owned by userID.
+func (r *RepoSqlLite) RenamePasskey(userID, id int, name string) error {
+	res, err := r.db.Exec("UPDATE webauthn_credentials SET name = ? WHERE id = ? AND user_id = ?", name, id, userID)
+	if err != nil {
+		return err
+	}
+	return passkeyAffected(res)
+}
+
+func (r *RepoSqlLite) DeletePasskey(userID, id int) error {
+	res, err := r.db.Exec("DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	return passkeyAffected(res)
+}
+
+func passkeyAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (r *RepoSqlLite) SaveWebAuthnChallenge(challenge domain.WebAuthnChallenge) error {
+	_, err := r.db.Exec("INSERT INTO webauthn_challenges (user_id, challenge_hash, type, expiration_date) VALUES (?,?,?,?)", challenge.UserId, challenge.ChallengeHash, challenge.Type, challenge.ExpirationDate)
+	return err
+}
+
+// ConsumeWebAuthnChallenge looks up and deletes a challenge in one go, so a
+// response can only be verified against it once. Expired challenges are
+// removed on the way.
+func (r *RepoSqlLite) ConsumeWebAuthnChallenge(challengeHash, typ string, now time.Time) (domain.WebAuthnChallenge, error) {
+	_, err := r.db.Exec("DELETE FROM webauthn_challenges WHERE julianday(expiration_date) < julianday(?)", now)
+	if err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+
+	var challenge domain.WebAuthnChallenge
+	err = r.db.QueryRow("SELECT id, user_id, challenge_hash, type, expiration_date FROM webauthn_challenges WHERE challenge_hash = ? AND type = ?", challengeHash, typ).
+		Scan(&challenge.Id, &challenge.UserId, &challenge.ChallengeHash, &challenge.Type, &challenge.ExpirationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.WebAuthnChallenge{}, domain.ErrInvalidPasskey
+	}
+	if err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+
+	res, err := r.db.Exec("DELETE FROM webauthn_challenges WHERE id = ?", challenge.Id)
+	if err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+	if n == 0 {
+		return domain.WebAuthnChallenge{}, domain.ErrInvalidPasskey
+	}
+	return challenge, nil
+}
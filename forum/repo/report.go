@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"forum/forum/domain"
+)
+
+// SaveReport files a report unless the reporter has an open report on the
+// same item already.
+func (r *RepoSqlLite) SaveReport(report domain.Report) error {
+	res, err := r.db.Exec(`
+		INSERT INTO reports (reporter_id, post_id, comment_id, reason, details, creation_date) VALUES (?,?,?,?,?,?)
+		ON CONFLICT (reporter_id, post_id, comment_id) WHERE resolution = '' DO NOTHING`,
+		report.ReporterId, report.PostId, report.CommentId, report.Reason, report.Details, report.CreationDate)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrAlreadyReported
+	}
+	return nil
+}
+
+const reportColumns = "rp.id, rp.reporter_id, COALESCE(u.username, ''), rp.post_id, rp.comment_id, rp.reason, rp.details, rp.resolution, rp.moderator_id, rp.creation_date, rp.resolved_date"
+
+// GetOpenReports returns all unresolved reports, oldest first.
+func (r *RepoSqlLite) GetOpenReports() ([]domain.Report, error) {
+	return r.queryReports("SELECT " + reportColumns + " FROM reports rp LEFT JOIN users u ON u.user_id = rp.reporter_id WHERE rp.resolution = '' ORDER BY rp.creation_date, rp.id")
+}
+
+// GetOpenReportsFor returns the unresolved reports on one post, or on one
+// comment when commentID is not 0.
+func (r *RepoSqlLite) GetOpenReportsFor(postID, commentID int) ([]domain.Report, error) {
+	return r.queryReports("SELECT "+reportColumns+" FROM reports rp LEFT JOIN users u ON u.user_id = rp.reporter_id WHERE rp.resolution = '' AND rp.post_id = ? AND rp.comment_id = ? ORDER BY rp.creation_date, rp.id", postID, commentID)
+}
+
+func (r *RepoSqlLite) queryReports(query string, args ...any) ([]domain.Report, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []domain.Report
+	for rows.Next() {
+		var report domain.Report
+		var resolved sql.NullTime
+		err = rows.Scan(&report.Id, &report.ReporterId, &report.ReporterName, &report.PostId, &report.CommentId, &report.Reason, &report.Details,
+			&report.Resolution, &report.ModeratorId, &report.CreationDate, &resolved)
+		if err != nil {
+			return nil, err
+		}
+		report.ResolvedDate = resolved.Time
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// ResolveReports closes the open reports on an item and returns how many
+// there were. entry, unless it is nil, records the decision.
+func (r *RepoSqlLite) ResolveReports(postID, commentID int, resolution domain.ModerationAction, moderatorID int, now time.Time, entry *domain.AuditEntry) (int64, error) {
+	var n int64
+	err := r.audited(entry, func(tx *sql.Tx) error {
+		res, err := tx.Exec("UPDATE reports SET resolution = ?, moderator_id = ?, resolved_date = ? WHERE resolution = '' AND post_id = ? AND comment_id = ?",
+			resolution, moderatorID, now, postID, commentID)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+func (r *RepoSqlLite) SetPostHidden(postID int, hidden bool, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE posts SET hidden = ? WHERE post_id = ?", hidden, postID)
+		return err
+	})
+}
+
+func (r *RepoSqlLite) SetCommentHidden(commentID int, hidden bool, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE comments SET hidden = ? WHERE comment_id = ?", hidden, commentID)
+		if err != nil {
+			return err
+		}
+		var postID int
+		err = tx.QueryRow("SELECT post_id FROM comments WHERE comment_id = ?", commentID).Scan(&postID)
+		if err != nil {
+			return err
+		}
+		return updateCommentCount(tx, postID)
+	})
+}
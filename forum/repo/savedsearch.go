@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"forum/forum/domain"
+)
+
+const savedSearchColumns = "id, user_id, name, query, notify, digest, last_post_id, creation_date"
+
+func scanSavedSearch(row rowScanner) (domain.SavedSearch, error) {
+	var s domain.SavedSearch
+	err := row.Scan(&s.Id, &s.UserId, &s.Name, &s.Query, &s.Notify, &s.Digest, &s.LastPostId, &s.CreationDate)
+	return s, err
+}
+
+func (r *RepoSqlLite) querySavedSearches(query string, args ...any) ([]domain.SavedSearch, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []domain.SavedSearch
+	for rows.Next() {
+		s, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+func (r *RepoSqlLite) SaveSavedSearch(search domain.SavedSearch) error {
+	_, err := r.db.Exec("INSERT INTO saved_searches (user_id, name, query, notify, digest, last_post_id, creation_date) VALUES (?,?,?,?,?,?,?)",
+		search.UserId, search.Name, search.Query, search.Notify, search.Digest, search.LastPostId, search.CreationDate)
+	return err
+}
+
+func (r *RepoSqlLite) CountSavedSearches(userID int) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM saved_searches WHERE user_id = ?", userID).Scan(&n)
+	return n, err
+}
+
+func (r *RepoSqlLite) GetSavedSearches(userID int) ([]domain.SavedSearch, error) {
+	return r.querySavedSearches("SELECT "+savedSearchColumns+" FROM saved_searches WHERE user_id = ? ORDER BY creation_date, id", userID)
+}
+
+// GetAlertingSearches returns the saved searches that notify their owner or
+// feed the digest, the ones worth matching new posts against.
+func (r *RepoSqlLite) GetAlertingSearches() ([]domain.SavedSearch, error) {
+	return r.querySavedSearches("SELECT " + savedSearchColumns + " FROM saved_searches WHERE notify = 1 OR digest = 1 ORDER BY id")
+}
+
+// UpdateSavedSearch and DeleteSavedSearch only touch searches owned by
+// userID.
+func (r *RepoSqlLite) UpdateSavedSearch(userID, id int, notify, digest bool) error {
+	res, err := r.db.Exec("UPDATE saved_searches SET notify = ?, digest = ? WHERE id = ? AND user_id = ?", notify, digest, id, userID)
+	if err != nil {
+		return err
+	}
+	return savedSearchAffected(res)
+}
+
+func (r *RepoSqlLite) DeleteSavedSearch(userID, id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM saved_searches WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	err = savedSearchAffected(res)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM search_alerts WHERE search_id = ?", id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func savedSearchAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// LatestPostID returns the id of the newest post, 0 when there are none.
+func (r *RepoSqlLite) LatestPostID() (int, error) {
+	var id int
+	err := r.db.QueryRow("SELECT COALESCE(MAX(post_id), 0) FROM posts").Scan(&id)
+	return id, err
+}
+
+// MatchNewPosts returns the ids of the posts after afterID, up to and
+// including upToID, that match the filter, leaving out the posts of
+// ownerID and those only their author may see.
+func (r *RepoSqlLite) MatchNewPosts(filter domain.SearchFilter, ownerID, afterID, upToID int) ([]int, error) {
+	from, match := "posts p", ""
+	var args []any
+	if len(filter.Terms) > 0 {
+		if !r.search {
+			return nil, domain.ErrSearchUnavailable
+		}
+		from = "posts_fts JOIN posts p ON p.post_id = posts_fts.rowid"
+		match = " AND posts_fts MATCH ?"
+		args = append(args, ftsQuery(filter.Terms))
+	}
+	conditions, filterArgs := searchFilter(filter, "p")
+	args = append(append(args, filterArgs...), afterID, upToID, ownerID)
+	rows, err := r.db.Query(`SELECT p.post_id FROM `+from+`
+		WHERE p.hidden = 0 AND p.shadowed = 0`+match+conditions+`
+		AND p.post_id > ? AND p.post_id <= ? AND p.user_id != ?
+		ORDER BY p.post_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SaveSearchAlerts records the posts found by a saved search and moves its
+// watermark to upToID, so that they are not matched again.
+func (r *RepoSqlLite) SaveSearchAlerts(search domain.SavedSearch, postIDs []int, upToID int, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, postID := range postIDs {
+		_, err = tx.Exec("INSERT OR IGNORE INTO search_alerts (search_id, owner_id, post_id, notify, pending, creation_date) VALUES (?,?,?,?,?,?)",
+			search.Id, search.UserId, postID, search.Notify, search.Digest, now)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("UPDATE saved_searches SET last_post_id = ? WHERE id = ?", upToID, search.Id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const searchAlertQuery = `SELECT a.id, a.search_id, s.name, a.owner_id, a.post_id, p.title, p.username, a.notify, a.pending, a.creation_date
+	FROM search_alerts a
+	JOIN saved_searches s ON s.id = a.search_id
+	JOIN posts p ON p.post_id = a.post_id
+	WHERE p.hidden = 0 AND p.shadowed = 0`
+
+func (r *RepoSqlLite) querySearchAlerts(query string, args ...any) ([]domain.SearchAlert, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []domain.SearchAlert
+	for rows.Next() {
+		var a domain.SearchAlert
+		err := rows.Scan(&a.Id, &a.SearchId, &a.SearchName, &a.OwnerId, &a.PostId, &a.Title, &a.Username, &a.Notify, &a.Pending, &a.CreationDate)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// GetSearchAlerts returns a page of the alerts shown to ownerID on the
+// notifications page, newest first.
+func (r *RepoSqlLite) GetSearchAlerts(ownerID int, req domain.PageRequest) ([]domain.SearchAlert, domain.Page, error) {
+	clause, pageArgs := pageClause("a.id", req)
+	alerts, err := r.querySearchAlerts(searchAlertQuery+" AND a.owner_id = ? AND a.notify = 1"+clause, append([]any{ownerID}, pageArgs...)...)
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	alerts, page := pageOf(alerts, req, func(a domain.SearchAlert) domain.Cursor { return domain.Cursor{Id: a.Id} })
+	return alerts, page, nil
+}
+
+// GetPendingAlerts returns the alerts waiting for the next digest, grouped
+// by owner. Those of owners who may not be mailed, because their address is
+// not verified or they are banned, are left waiting.
+func (r *RepoSqlLite) GetPendingAlerts() ([]domain.SearchAlert, error) {
+	return r.querySearchAlerts(searchAlertQuery + ` AND a.pending = 1
+		AND a.owner_id IN (SELECT user_id FROM users WHERE email_verified = 1 AND banned = 0)
+		ORDER BY a.owner_id, a.search_id, a.id`)
+}
+
+// ClearPendingAlerts takes alerts out of the digest once it is sent.
+func (r *RepoSqlLite) ClearPendingAlerts(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	_, err := r.db.Exec("UPDATE search_alerts SET pending = 0 WHERE id IN ("+placeholders(len(ids))+")", args...)
+	return err
+}
@@ -0,0 +1,279 @@
+package repo
+
+import (
+	"log"
+	"strings"
+
+	"forum/forum/domain"
+)
+
+// searchTriggers keep the full-text index in step with posts and comments.
+// They are dropped again when the server runs on a SQLite built without
+// FTS5, since every write to posts or comments would fail otherwise.
+var searchTriggers = map[string]string{
+	"posts_fts_insert": `AFTER INSERT ON posts BEGIN
+		INSERT INTO posts_fts (rowid, title, content) VALUES (new.post_id, new.title, new.content);
+	END`,
+	"posts_fts_delete": `AFTER DELETE ON posts BEGIN
+		INSERT INTO posts_fts (posts_fts, rowid, title, content) VALUES ('delete', old.post_id, old.title, old.content);
+	END`,
+	"posts_fts_update": `AFTER UPDATE OF title, content ON posts BEGIN
+		INSERT INTO posts_fts (posts_fts, rowid, title, content) VALUES ('delete', old.post_id, old.title, old.content);
+		INSERT INTO posts_fts (rowid, title, content) VALUES (new.post_id, new.title, new.content);
+	END`,
+	"comments_fts_insert": `AFTER INSERT ON comments BEGIN
+		INSERT INTO comments_fts (rowid, content) VALUES (new.comment_id, new.content);
+	END`,
+	"comments_fts_delete": `AFTER DELETE ON comments BEGIN
+		INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.comment_id, old.content);
+	END`,
+	"comments_fts_update": `AFTER UPDATE OF content ON comments BEGIN
+		INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.comment_id, old.content);
+		INSERT INTO comments_fts (rowid, content) VALUES (new.comment_id, new.content);
+	END`,
+}
+
+// createSearchIndex sets up the full-text index of posts and comments, and
+// fills it when it is new or missed writes while the server ran without
+// FTS5. Search is left off when SQLite was built without FTS5.
+func (r *RepoSqlLite) createSearchIndex() error {
+	err := r.db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&r.search)
+	if err != nil {
+		return err
+	}
+	if !r.search {
+		log.Println("search is disabled: SQLite was built without FTS5, build with -tags sqlite_fts5")
+		for name := range searchTriggers {
+			_, err = r.db.Exec("DROP TRIGGER IF EXISTS " + name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	names := make([]any, 0, len(searchTriggers))
+	for name := range searchTriggers {
+		names = append(names, name)
+	}
+	var current int
+	err = r.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ("+placeholders(len(names))+")", names...).Scan(&current)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+			title, content, content='posts', content_rowid='post_id', tokenize='unicode61 remove_diacritics 2'
+		);
+		CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+			content, content='comments', content_rowid='comment_id', tokenize='unicode61 remove_diacritics 2'
+		);
+	`)
+	if err != nil {
+		return err
+	}
+	for name, body := range searchTriggers {
+		_, err = r.db.Exec("CREATE TRIGGER IF NOT EXISTS " + name + " " + body)
+		if err != nil {
+			return err
+		}
+	}
+	if current < len(searchTriggers) {
+		return r.RebuildSearchIndex()
+	}
+	return nil
+}
+
+// RebuildSearchIndex indexes every post and comment again from scratch.
+func (r *RepoSqlLite) RebuildSearchIndex() error {
+	if !r.search {
+		return domain.ErrSearchUnavailable
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');
+		INSERT INTO comments_fts (comments_fts) VALUES ('rebuild');
+	`)
+	return err
+}
+
+// ftsQuery turns the terms of a search into an FTS5 query matching all of
+// them. Each term is quoted so that FTS5 syntax in it is taken literally.
+func ftsQuery(terms []domain.SearchTerm) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+		if term.Prefix {
+			quoted[i] += "*"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// defaultImage is the picture shown with posts written without an image.
+const defaultImage = "../static/Gallery/default.png"
+
+// compareOps lists the comparisons searchFilter may put in a query.
+var compareOps = map[domain.CompareOp]bool{
+	domain.OpEqual:        true,
+	domain.OpGreater:      true,
+	domain.OpGreaterEqual: true,
+	domain.OpLess:         true,
+	domain.OpLessEqual:    true,
+}
+
+// searchFilter compiles the operators of a search into conditions on the
+// post p and, for the author and dates, on the item written, p itself or a
+// comment.
+func searchFilter(filter domain.SearchFilter, written string) (string, []any) {
+	var conditions []string
+	var args []any
+	if filter.Author != "" {
+		conditions = append(conditions, written+".username = ? COLLATE NOCASE")
+		args = append(args, filter.Author)
+	}
+	for _, category := range filter.Categories {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM post_categories pc
+			JOIN categories cat ON cat.category_id = pc.category_id
+			WHERE pc.post_id = p.post_id AND (cat.slug = ? OR cat.name = ? COLLATE NOCASE))`)
+		args = append(args, category, category)
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM post_tags pt
+			JOIN tags t ON t.tag_id = pt.tag_id
+			WHERE pt.post_id = p.post_id AND t.name = ? AND t.banned = 0)`)
+		args = append(args, tag)
+	}
+	if !filter.Before.IsZero() {
+		conditions = append(conditions, written+".creation_date < ?")
+		args = append(args, filter.Before)
+	}
+	if !filter.After.IsZero() {
+		conditions = append(conditions, written+".creation_date >= ?")
+		args = append(args, filter.After)
+	}
+	for _, c := range filter.Likes {
+		if !compareOps[c.Op] {
+			continue
+		}
+		conditions = append(conditions, "(SELECT COUNT(*) FROM likes l WHERE l.post_id = p.post_id) "+string(c.Op)+" ?")
+		args = append(args, c.Value)
+	}
+	if filter.HasImage {
+		conditions = append(conditions, "COALESCE(p.imagefield, '') NOT IN ('', ?)")
+		args = append(args, defaultImage)
+	}
+	if filter.Unanswered {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM comments answer WHERE answer.post_id = p.post_id AND answer.hidden = 0 AND answer.shadowed = 0)")
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// The rank of a result is its bm25 score negated, so that better matches
+// have higher keys; matches in the title of a post count ten times more.
+// Searches with operators only have no rank and list the newest first.
+const (
+	postRank    = "-bm25(posts_fts, 10.0, 1.0)"
+	commentRank = "-bm25(comments_fts)"
+	snippetSize = 24
+	excerptSize = 160
+)
+
+// SearchPosts returns a page of the posts the viewer may see that match the
+// filter, best match first.
+func (r *RepoSqlLite) SearchPosts(filter domain.SearchFilter, viewerID int, req domain.PageRequest) ([]domain.SearchResult, domain.Page, error) {
+	var args []any
+	rank, snippet, from, match := "0", "substr(p.content, 1, ?)", "posts p", ""
+	if len(filter.Terms) > 0 {
+		if !r.search {
+			return nil, domain.Page{}, domain.ErrSearchUnavailable
+		}
+		rank, snippet = postRank, "snippet(posts_fts, 1, ?, ?, '…', ?)"
+		from = "posts_fts JOIN posts p ON p.post_id = posts_fts.rowid"
+		match = " AND posts_fts MATCH ?"
+		args = append(args, domain.HighlightStart, domain.HighlightEnd, snippetSize, viewerID, ftsQuery(filter.Terms))
+	} else {
+		args = append(args, excerptSize, viewerID)
+	}
+	conditions, filterArgs := searchFilter(filter, "p")
+	clause, pageArgs := pageClause("p.post_id", req)
+	if len(filter.Terms) > 0 {
+		clause, pageArgs = keyedPageClause(rank, "p.post_id", req)
+	}
+	args = append(append(args, filterArgs...), pageArgs...)
+	return r.querySearch(`SELECT p.post_id, 0, p.title, `+snippet+`, p.username, p.creation_date, `+rank+`
+		FROM `+from+`
+		WHERE p.hidden = 0 AND (p.shadowed = 0 OR p.user_id = ?)`+match+conditions+clause, req, args...)
+}
+
+// SearchComments returns a page of the comments the viewer may see, on
+// posts they may see, that match the filter, best match first.
+func (r *RepoSqlLite) SearchComments(filter domain.SearchFilter, viewerID int, req domain.PageRequest) ([]domain.SearchResult, domain.Page, error) {
+	var args []any
+	rank, snippet, from, match := "0", "substr(c.content, 1, ?)", "comments c", ""
+	if len(filter.Terms) > 0 {
+		if !r.search {
+			return nil, domain.Page{}, domain.ErrSearchUnavailable
+		}
+		rank, snippet = commentRank, "snippet(comments_fts, 0, ?, ?, '…', ?)"
+		from = "comments_fts JOIN comments c ON c.comment_id = comments_fts.rowid"
+		match = " AND comments_fts MATCH ?"
+		args = append(args, domain.HighlightStart, domain.HighlightEnd, snippetSize, viewerID, viewerID, ftsQuery(filter.Terms))
+	} else {
+		args = append(args, excerptSize, viewerID, viewerID)
+	}
+	conditions, filterArgs := searchFilter(filter, "c")
+	clause, pageArgs := pageClause("c.comment_id", req)
+	if len(filter.Terms) > 0 {
+		clause, pageArgs = keyedPageClause(rank, "c.comment_id", req)
+	}
+	args = append(append(args, filterArgs...), pageArgs...)
+	return r.querySearch(`SELECT c.post_id, c.comment_id, p.title, `+snippet+`, c.username, c.creation_date, `+rank+`
+		FROM `+from+`
+		JOIN posts p ON p.post_id = c.post_id
+		WHERE c.hidden = 0 AND (c.shadowed = 0 OR c.user_id = ?)
+		AND p.hidden = 0 AND (p.shadowed = 0 OR p.user_id = ?)`+match+conditions+clause, req, args...)
+}
+
+// querySearch runs a search query ending in pageClause or keyedPageClause
+// and returns the page of results.
+func (r *RepoSqlLite) querySearch(query string, req domain.PageRequest, args ...any) ([]domain.SearchResult, domain.Page, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	defer rows.Close()
+
+	type rankedResult struct {
+		result domain.SearchResult
+		rank   float64
+	}
+	var ranked []rankedResult
+	for rows.Next() {
+		var rr rankedResult
+		res := &rr.result
+		err := rows.Scan(&res.PostId, &res.CommentId, &res.Title, &res.Snippet, &res.Username, &res.CreationDate, &rr.rank)
+		if err != nil {
+			return nil, domain.Page{}, err
+		}
+		ranked = append(ranked, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	ranked, page := pageOf(ranked, req, func(rr rankedResult) domain.Cursor {
+		id := rr.result.CommentId
+		if id == 0 {
+			id = rr.result.PostId
+		}
+		return domain.Cursor{Id: id, Key: rr.rank}
+	})
+	results := make([]domain.SearchResult, len(ranked))
+	for i, rr := range ranked {
+		results[i] = rr.result
+	}
+	return results, page, nil
+}
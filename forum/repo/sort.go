@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/forum/domain"
+)
+
+// migratePostScores indexes the columns posts are sorted by and computes
+// them for posts written before they existed, which still have a hot_score
+// of 0.
+func (r *RepoSqlLite) migratePostScores() error {
+	_, err := r.db.Exec(`
+		CREATE INDEX IF NOT EXISTS posts_hot ON posts (hot_score, post_id);
+		CREATE INDEX IF NOT EXISTS posts_controversy ON posts (controversy, post_id);
+		CREATE INDEX IF NOT EXISTS posts_comment_count ON posts (comment_count, post_id);
+		CREATE INDEX IF NOT EXISTS posts_creation_date ON posts (creation_date);
+		CREATE INDEX IF NOT EXISTS comments_post ON comments (post_id);
+	`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Query("SELECT post_id FROM posts WHERE hot_score = 0")
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err = r.updatePostScores(id)
+		if err != nil {
+			return err
+		}
+		err = updateCommentCount(r.db, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatePostScores recomputes the hot and controversy scores of a post
+// after its likes or dislikes changed.
+func (r *RepoSqlLite) updatePostScores(postID int) error {
+	var likes, dislikes int
+	var created time.Time
+	err := r.db.QueryRow("SELECT COALESCE(likes, 0), COALESCE(dislikes, 0), creation_date FROM posts WHERE post_id = ?", postID).Scan(&likes, &dislikes, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("UPDATE posts SET hot_score = ?, controversy = ? WHERE post_id = ?",
+		domain.HotScore(likes, dislikes, created), domain.Controversy(likes, dislikes), postID)
+	return err
+}
+
+// updateCommentCount recounts the comments of a post everyone may see,
+// after one was added, deleted, hidden or shown again.
+func updateCommentCount(db execer, postID int) error {
+	_, err := db.Exec("UPDATE posts SET comment_count = (SELECT COUNT(*) FROM comments WHERE post_id = ? AND hidden = 0 AND shadowed = 0) WHERE post_id = ?", postID, postID)
+	return err
+}
+
+// sortKey returns the column, or expression, posts are ordered by under
+// sort, and "" for newest first.
+func sortKey(sort domain.PostSort) string {
+	switch sort {
+	case domain.SortHot:
+		return "hot_score"
+	case domain.SortTop:
+		return "(COALESCE(likes, 0) - COALESCE(dislikes, 0))"
+	case domain.SortControversial:
+		return "controversy"
+	case domain.SortComments:
+		return "comment_count"
+	}
+	return ""
+}
+
+// sortedPostPage returns the page of the posts matching the where
+// conditions in the order of filter.Sort, limited to filter.Period for
+// SortTop, with their categories and tags.
+func (r *RepoSqlLite) sortedPostPage(where string, filter domain.PostFilter, req domain.PageRequest, args ...any) ([]domain.Posts, domain.Page, error) {
+	key := sortKey(filter.Sort)
+	if key == "" {
+		return r.queryPostPage("SELECT "+postColumns+" FROM posts WHERE "+where, req, args...)
+	}
+	if filter.Sort == domain.SortTop {
+		if since := filter.Period.Since(time.Now()); !since.IsZero() {
+			where += " AND creation_date >= ?"
+			args = append(args, since)
+		}
+	}
+	clause, pageArgs := keyedPageClause(key, "post_id", req)
+	rows, err := r.db.Query("SELECT "+postColumns+", "+key+" FROM posts WHERE "+where+clause, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, domain.Page{}, err
+	}
+	defer rows.Close()
+
+	type keyedPost struct {
+		post domain.Posts
+		key  float64
+	}
+	var keyed []keyedPost
+	for rows.Next() {
+		var k keyedPost
+		p := &k.post
+		err := rows.Scan(&p.PostId, &p.UserId, &p.Username, &p.Title, &p.Content, &p.ContentHTML, &p.ImageField, &p.CreationDate, &p.Likes, &p.Dislikes, &k.key)
+		if err != nil {
+			return nil, domain.Page{}, err
+		}
+		keyed = append(keyed, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.Page{}, err
+	}
+
+	keyed, page := pageOf(keyed, req, func(k keyedPost) domain.Cursor { return domain.Cursor{Id: k.post.PostId, Key: k.key} })
+	posts := make([]domain.Posts, len(keyed))
+	for i, k := range keyed {
+		posts[i] = k.post
+	}
+	return posts, page, r.attachPostDetails(posts)
+}
@@ -4,14 +4,18 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"forum/forum/domain"
+	"forum/forum/markdown"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type RepoSqlLite struct {
 	db *sql.DB
+	// search tells whether SQLite has FTS5 and so the search index exists.
+	search bool
 }
 
 func NewDatabase() (*RepoSqlLite, error) {
@@ -24,7 +28,14 @@ func NewDatabase() (*RepoSqlLite, error) {
 			user_id INTEGER PRIMARY KEY AUTOINCREMENT,
 			username TEXT NOT NULL UNIQUE,
 			email TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL 
+			password TEXT NOT NULL,
+			email_verified INTEGER NOT NULL DEFAULT 0,
+			role TEXT NOT NULL DEFAULT 'user',
+			registration_date DATETIME DEFAULT CURRENT_TIMESTAMP,
+			suspended_until TIMESTAMP,
+			banned INTEGER NOT NULL DEFAULT 0,
+			ban_reason TEXT NOT NULL DEFAULT '',
+			shadow_banned INTEGER NOT NULL DEFAULT 0
 		);
 		CREATE TABLE IF NOT EXISTS posts (
 			post_id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -60,34 +71,305 @@ func NewDatabase() (*RepoSqlLite, error) {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			username TEXT NOT NULL,
-			session_id TEXT NOT NULL
+			session_id TEXT NOT NULL,
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip TEXT NOT NULL DEFAULT '',
+			creation_date TIMESTAMP,
+			last_seen TIMESTAMP,
+			expiration_date TIMESTAMP
 		);
+		CREATE TABLE IF NOT EXISTS password_resets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			creation_date TIMESTAMP NOT NULL,
+			expiration_date TIMESTAMP NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS user_totp (
+			user_id INTEGER PRIMARY KEY,
+			secret TEXT NOT NULL,
+			enabled INTEGER NOT NULL DEFAULT 0,
+			last_counter INTEGER NOT NULL DEFAULT 0,
+			creation_date TIMESTAMP NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			code_hash TEXT NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS login_challenges (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expiration_date TIMESTAMP NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS email_verifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			creation_date TIMESTAMP NOT NULL,
+			expiration_date TIMESTAMP NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS webauthn_credentials (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			credential_id BLOB NOT NULL UNIQUE,
+			public_key BLOB NOT NULL,
+			sign_count INTEGER NOT NULL DEFAULT 0,
+			name TEXT NOT NULL,
+			creation_date TIMESTAMP NOT NULL,
+			last_used TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT NOT NULL,
+			creation_date TIMESTAMP NOT NULL,
+			UNIQUE (provider, subject),
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE TABLE IF NOT EXISTS oauth_states (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			state_hash TEXT NOT NULL UNIQUE,
+			provider TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			expiration_date TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS auth_throttles (
+			key TEXT PRIMARY KEY,
+			count INTEGER NOT NULL,
+			last_event TIMESTAMP NOT NULL,
+			blocked_until TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS webauthn_challenges (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			challenge_hash TEXT NOT NULL UNIQUE,
+			type TEXT NOT NULL,
+			expiration_date TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reporter_id INTEGER NOT NULL,
+			post_id INTEGER NOT NULL,
+			comment_id INTEGER NOT NULL DEFAULT 0,
+			reason TEXT NOT NULL,
+			details TEXT NOT NULL DEFAULT '',
+			resolution TEXT NOT NULL DEFAULT '',
+			moderator_id INTEGER NOT NULL DEFAULT 0,
+			creation_date TIMESTAMP NOT NULL,
+			resolved_date TIMESTAMP,
+			FOREIGN KEY (reporter_id) REFERENCES users(user_id)
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS reports_open ON reports (reporter_id, post_id, comment_id) WHERE resolution = '';
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor_id INTEGER NOT NULL,
+			actor_name TEXT NOT NULL,
+			action TEXT NOT NULL,
+			target_type TEXT NOT NULL,
+			target_id INTEGER NOT NULL,
+			before TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			creation_date TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS audit_log_date ON audit_log (creation_date);
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'the audit log is append-only');
+		END;
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'the audit log is append-only');
+		END;
+		CREATE TABLE IF NOT EXISTS categories (
+			category_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			position INTEGER NOT NULL DEFAULT 0,
+			archived INTEGER NOT NULL DEFAULT 0,
+			post_policy TEXT NOT NULL DEFAULT 'everyone',
+			allow_comments INTEGER NOT NULL DEFAULT 1,
+			read_only INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS post_categories (
+			post_id INTEGER NOT NULL,
+			category_id INTEGER NOT NULL,
+			PRIMARY KEY (post_id, category_id),
+			FOREIGN KEY (post_id) REFERENCES posts(post_id),
+			FOREIGN KEY (category_id) REFERENCES categories(category_id)
+		);
+		CREATE INDEX IF NOT EXISTS post_categories_category ON post_categories (category_id, post_id);
+		CREATE TABLE IF NOT EXISTS tags (
+			tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			banned INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS post_tags (
+			post_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (post_id, tag_id),
+			FOREIGN KEY (post_id) REFERENCES posts(post_id),
+			FOREIGN KEY (tag_id) REFERENCES tags(tag_id)
+		);
+		CREATE INDEX IF NOT EXISTS post_tags_tag ON post_tags (tag_id, post_id);
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,
+			notify INTEGER NOT NULL DEFAULT 1,
+			digest INTEGER NOT NULL DEFAULT 0,
+			last_post_id INTEGER NOT NULL DEFAULT 0,
+			creation_date TIMESTAMP NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+		CREATE INDEX IF NOT EXISTS saved_searches_user ON saved_searches (user_id);
+		CREATE TABLE IF NOT EXISTS search_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			search_id INTEGER NOT NULL,
+			owner_id INTEGER NOT NULL,
+			post_id INTEGER NOT NULL,
+			notify INTEGER NOT NULL DEFAULT 1,
+			pending INTEGER NOT NULL DEFAULT 0,
+			creation_date TIMESTAMP NOT NULL,
+			UNIQUE (search_id, post_id),
+			FOREIGN KEY (search_id) REFERENCES saved_searches(id),
+			FOREIGN KEY (post_id) REFERENCES posts(post_id)
+		);
+		CREATE INDEX IF NOT EXISTS search_alerts_owner ON search_alerts (owner_id, id);
+		CREATE INDEX IF NOT EXISTS search_alerts_pending ON search_alerts (pending, owner_id);
 	`)
-	return &RepoSqlLite{
+	if err != nil {
+		return nil, err
+	}
+	r := &RepoSqlLite{
 		db: db,
-	}, err
+	}
+	err = r.addMissingColumns()
+	if err != nil {
+		return nil, err
+	}
+	err = r.migrateCategories()
+	if err != nil {
+		return nil, err
+	}
+	err = r.migratePostScores()
+	if err != nil {
+		return nil, err
+	}
+	err = r.renderStaleContent()
+	if err != nil {
+		return nil, err
+	}
+	err = r.createSearchIndex()
+	return r, err
 }
 
-func (r *RepoSqlLite) GetUser(username string) (domain.User, error) {
+// columns lists columns added after a table was first created, so that
+// databases created by older versions are upgraded on startup.
+var columns = []struct {
+	table, name, definition string
+}{
+	{"session", "creation_date", "TIMESTAMP"},
+	{"session", "expiration_date", "TIMESTAMP"},
+	{"session", "user_agent", "TEXT NOT NULL DEFAULT ''"},
+	{"session", "ip", "TEXT NOT NULL DEFAULT ''"},
+	{"session", "last_seen", "TIMESTAMP"},
+	// Accounts created before email verification existed are considered verified.
+	{"users", "email_verified", "INTEGER NOT NULL DEFAULT 1"},
+	{"users", "role", "TEXT NOT NULL DEFAULT 'user'"},
+	{"users", "suspended_until", "TIMESTAMP"},
+	{"users", "banned", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "ban_reason", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "shadow_banned", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "hidden", "INTEGER NOT NULL DEFAULT 0"},
+	{"comments", "hidden", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "shadowed", "INTEGER NOT NULL DEFAULT 0"},
+	{"comments", "shadowed", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "category_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "likes", "INTEGER DEFAULT 0"},
+	{"posts", "dislikes", "INTEGER DEFAULT 0"},
+	{"comments", "likes", "INTEGER DEFAULT 0"},
+	{"comments", "dislikes", "INTEGER DEFAULT 0"},
+	{"categories", "slug", "TEXT NOT NULL DEFAULT ''"},
+	{"categories", "description", "TEXT NOT NULL DEFAULT ''"},
+	{"categories", "position", "INTEGER NOT NULL DEFAULT 0"},
+	{"categories", "archived", "INTEGER NOT NULL DEFAULT 0"},
+	{"categories", "post_policy", "TEXT NOT NULL DEFAULT 'everyone'"},
+	{"categories", "allow_comments", "INTEGER NOT NULL DEFAULT 1"},
+	{"categories", "read_only", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "hot_score", "REAL NOT NULL DEFAULT 0"},
+	{"posts", "controversy", "REAL NOT NULL DEFAULT 0"},
+	{"posts", "comment_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "content_html", "TEXT NOT NULL DEFAULT ''"},
+	{"posts", "content_version", "INTEGER NOT NULL DEFAULT 0"},
+	{"comments", "content_html", "TEXT NOT NULL DEFAULT ''"},
+	{"comments", "content_version", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+func (r *RepoSqlLite) addMissingColumns() error {
+	for _, c := range columns {
+		var count int
+		err := r.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", c.table, c.name).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		_, err = r.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const userColumns = "u.user_id, u.username, u.email, u.password, u.email_verified, u.role, u.registration_date, u.suspended_until, u.banned, u.ban_reason, u.shadow_banned"
+
+func scanUser(row rowScanner) (domain.User, error) {
 	var user domain.User
-	err := r.db.QueryRow("SELECT user_id, username, email, password, registration_date FROM users WHERE username = ?", username).Scan(
+	var registration, suspendedUntil sql.NullTime
+	err := row.Scan(
 		&user.UserId,
 		&user.Username,
 		&user.Email,
 		&user.Password,
-		&user.RegistrationDate,
+		&user.EmailVerified,
+		&user.Role,
+		&registration,
+		&suspendedUntil,
+		&user.Banned,
+		&user.BanReason,
+		&user.ShadowBanned,
 	)
+	user.RegistrationDate = registration.Time
+	user.SuspendedUntil = suspendedUntil.Time
+	return user, err
+}
+
+func (r *RepoSqlLite) GetUser(username string) (domain.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users u WHERE u.username = ?", username))
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.User{}, domain.ErrInvalidUser
 	}
-	return user, nil
+	return user, err
 }
 
 func (r *RepoSqlLite) GetUserByEmail(email string) (domain.User, error) {
-	var user domain.User
-
-	err := r.db.QueryRow("SELECT username, email, password FROM users WHERE email = ?", email).
-		Scan(&user.Username, &user.Email, &user.Password)
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users u WHERE u.email = ?", email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, domain.ErrInvalidUser
@@ -99,140 +381,300 @@ func (r *RepoSqlLite) GetUserByEmail(email string) (domain.User, error) {
 }
 
 func (r *RepoSqlLite) SaveSession(session domain.Session) error {
-	_, err := r.db.Exec("INSERT OR REPLACE INTO session (user_id, username, session_id, creation_date , expiration_date) VALUES (?,?,?,? ,?)", session.UserId, session.Username, session.SessionId, session.CreationDate, session.ExpiritionDate)
+	_, err := r.db.Exec("INSERT OR REPLACE INTO session (user_id, username, session_id, user_agent, ip, creation_date, last_seen, expiration_date) VALUES (?,?,?,?,?,?,?,?)", session.UserId, session.Username, session.SessionId, session.UserAgent, session.IP, session.CreationDate, session.LastSeen, session.ExpiritionDate)
 	return err
 }
 
-func (r *RepoSqlLite) GetSession(sessionID string) (domain.Session, error) {
+const sessionColumns = "s.id, s.user_id, s.username, u.email_verified, u.role, u.suspended_until, u.shadow_banned, s.session_id, s.user_agent, s.ip, s.creation_date, s.last_seen, s.expiration_date"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner) (domain.Session, error) {
 	var session domain.Session
-	err := r.db.QueryRow("SELECT user_id, username, session_id  FROM session WHERE session_id = ?", sessionID).Scan(
+	var suspendedUntil, creation, lastSeen, expiration sql.NullTime
+	err := row.Scan(
+		&session.Id,
 		&session.UserId,
 		&session.Username,
+		&session.EmailVerified,
+		&session.Role,
+		&suspendedUntil,
+		&session.ShadowBanned,
 		&session.SessionId,
+		&session.UserAgent,
+		&session.IP,
+		&creation,
+		&lastSeen,
+		&expiration,
 	)
+	// Rows written before expiry was tracked have NULL dates and are
+	// returned with zero times, which the business layer treats as expired.
+	session.SuspendedUntil = suspendedUntil.Time
+	session.CreationDate = creation.Time
+	session.LastSeen = lastSeen.Time
+	session.ExpiritionDate = expiration.Time
+	return session, err
+}
+
+func (r *RepoSqlLite) GetSession(sessionID string) (domain.Session, error) {
+	session, err := scanSession(r.db.QueryRow("SELECT "+sessionColumns+" FROM session s JOIN users u ON u.user_id = s.user_id WHERE s.session_id = ?", sessionID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.Session{}, domain.ErrSessionNotFound
 	}
 	return session, err
 }
 
-func (r *RepoSqlLite) SaveUser(user domain.User) error {
-	_, err := r.db.Exec("INSERT INTO users (username, email, password) VALUES (?,?,?)", user.Username, user.Email, user.Password)
+func (r *RepoSqlLite) RenewSession(sessionID string, lastSeen, expiration time.Time) error {
+	_, err := r.db.Exec("UPDATE session SET last_seen = ?, expiration_date = ? WHERE session_id = ?", lastSeen, expiration, sessionID)
 	return err
 }
 
-func (r *RepoSqlLite) SavePosts(posts domain.Posts) error {
-	_, err := r.db.Exec("INSERT INTO posts (user_id, username, category, title, content, category_id ,imagefield, creation_date) VALUES (?,?,?,?,?,?,?,?)", posts.UserId, posts.Username, posts.Category, posts.Title, posts.Content, posts.CategoryId, posts.ImageField, posts.CreationDate)
-	return err
+// GetUserSessions lists the sessions of a user, most recently used first.
+func (r *RepoSqlLite) GetUserSessions(userID int) ([]domain.Session, error) {
+	var sessions []domain.Session
+	rows, err := r.db.Query("SELECT "+sessionColumns+" FROM session s JOIN users u ON u.user_id = s.user_id WHERE s.user_id = ? ORDER BY s.last_seen DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
 }
 
-func (r *RepoSqlLite) EditPost(postId int, post domain.Posts) error {
-	_, err := r.db.Exec("UPDATE posts SET title=?, content=?, imagefield=? WHERE post_id=?", post.Title, post.Content, post.ImageField, postId)
+func (r *RepoSqlLite) DeleteUserSession(userID, id int) error {
+	res, err := r.db.Exec("DELETE FROM session WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
 
+func (r *RepoSqlLite) DeleteSession(sessionID string) error {
+	_, err := r.db.Exec("DELETE FROM session WHERE session_id = ?", sessionID)
 	return err
 }
 
-func (r *RepoSqlLite) EditComment(commentId int, comment domain.Comments) error {
-	_, err := r.db.Exec("UPDATE comments SET content=? WHERE comment_id=?", comment.Content, commentId)
-
+func (r *RepoSqlLite) InvalidateOtherSessions(userID int, sessionID string) error {
+	_, err := r.db.Exec("DELETE FROM session WHERE user_id = ? AND session_id <> ?", userID, sessionID)
 	return err
 }
 
-func (r *RepoSqlLite) GetPosts() ([]domain.Posts, error) {
-	var posts []domain.Posts
-	rows, err := r.db.Query("SELECT post_id, user_id, username, category, title, content, imagefield, creation_date, likes, dislikes FROM posts")
+// DeleteExpiredSessions removes sessions whose expiration date is in the past
+// and returns how many rows were purged.
+func (r *RepoSqlLite) DeleteExpiredSessions(now time.Time) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM session WHERE expiration_date IS NULL OR julianday(expiration_date) < julianday(?)", now)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
+	return res.RowsAffected()
+}
 
-	for rows.Next() {
-		var p domain.Posts
-		err := rows.Scan(&p.PostId, &p.UserId, &p.Username, &p.Category, &p.Title, &p.Content, &p.ImageField, &p.CreationDate, &p.Likes, &p.Dislikes)
-		if err != nil {
-			return nil, err
-		}
+func (r *RepoSqlLite) SaveUser(user domain.User) error {
+	_, err := r.db.Exec("INSERT INTO users (username, email, password, email_verified) VALUES (?,?,?,?)", user.Username, user.Email, user.Password, user.EmailVerified)
+	return err
+}
 
-		posts = append(posts, p)
+// SavePosts stores a new post and files it under its categories and tags.
+// The category and category_id columns predate post_categories and are NOT
+// NULL in existing databases, so they keep the first category.
+func (r *RepoSqlLite) SavePosts(posts domain.Posts) error {
+	if len(posts.Categories) == 0 {
+		return domain.ErrNoCategory
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	res, err := tx.Exec("INSERT INTO posts (user_id, username, category, title, content, content_html, content_version, category_id ,imagefield, creation_date, shadowed, hot_score) VALUES (?,?,?,?,?,?,?,?,?,?,?,?)", posts.UserId, posts.Username, posts.Categories[0].Name, posts.Title, posts.Content, markdown.Render(posts.Content), markdown.Version, posts.Categories[0].Id, posts.ImageField, posts.CreationDate, posts.Shadowed, domain.HotScore(0, 0, posts.CreationDate))
+	if err != nil {
+		return err
 	}
+	postID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	err = setPostCategories(tx, int(postID), posts.Categories)
+	if err != nil {
+		return err
+	}
+	err = setPostTags(tx, int(postID), posts.Tags)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	return posts, nil
+// EditPost and EditComment write entry, unless it is nil, along with the
+// change.
+func (r *RepoSqlLite) EditPost(postId int, post domain.Posts, entry *domain.AuditEntry) error {
+	if len(post.Categories) == 0 {
+		return domain.ErrNoCategory
+	}
+	return r.audited(entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE posts SET title=?, content=?, content_html=?, content_version=?, imagefield=?, category=?, category_id=? WHERE post_id=?", post.Title, post.Content, markdown.Render(post.Content), markdown.Version, post.ImageField, post.Categories[0].Name, post.Categories[0].Id, postId)
+		if err != nil {
+			return err
+		}
+		err = setPostCategories(tx, postId, post.Categories)
+		if err != nil {
+			return err
+		}
+		return setPostTags(tx, postId, post.Tags)
+	})
 }
 
-func (r *RepoSqlLite) GetUserPosts(userID int) ([]domain.Posts, error) {
-	var posts []domain.Posts
-	rows, err := r.db.Query("SELECT post_id, user_id, username, category, title, content, imagefield, category_id, creation_date ,likes, dislikes FROM posts WHERE user_id = ?", userID)
+func (r *RepoSqlLite) EditComment(commentId int, comment domain.Comments, entry *domain.AuditEntry) error {
+	return r.audited(entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE comments SET content=?, content_html=?, content_version=? WHERE comment_id=?", comment.Content, markdown.Render(comment.Content), markdown.Version, commentId)
+		return err
+	})
+}
+
+// visibleTo limits a query on posts or comments to those the user with
+// viewerID may see in listings: nothing hidden by moderators, and shadowed
+// content only if it is their own.
+const visibleTo = "hidden = 0 AND (shadowed = 0 OR user_id = ?)"
+
+const postColumns = "post_id, user_id, username, title, content, content_html, imagefield, creation_date, likes, dislikes"
+
+// queryPostPage runs a query selecting postColumns from posts, completed by
+// pageClause, and returns the page of posts with their categories and tags.
+func (r *RepoSqlLite) queryPostPage(query string, req domain.PageRequest, args ...any) ([]domain.Posts, domain.Page, error) {
+	clause, pageArgs := pageClause("post_id", req)
+	rows, err := r.db.Query(query+clause, append(args, pageArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
+	var posts []domain.Posts
 	for rows.Next() {
 		var p domain.Posts
-		err := rows.Scan(&p.PostId, &p.UserId, &p.Username, &p.Category, &p.Title, &p.Content, &p.ImageField, &p.CategoryId, &p.CreationDate, &p.Likes, &p.Dislikes)
+		err := rows.Scan(&p.PostId, &p.UserId, &p.Username, &p.Title, &p.Content, &p.ContentHTML, &p.ImageField, &p.CreationDate, &p.Likes, &p.Dislikes)
 		if err != nil {
-			return nil, err
+			return nil, domain.Page{}, err
 		}
 
 		posts = append(posts, p)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 
-	return posts, nil
+	posts, page := pageOf(posts, req, func(p domain.Posts) domain.Cursor { return domain.Cursor{Id: p.PostId} })
+	return posts, page, r.attachPostDetails(posts)
 }
 
-func (r *RepoSqlLite) DeletePost(postID int) error {
-	_, err := r.db.Exec("DELETE FROM posts WHERE post_id = ?", postID)
+// attachPostDetails fills in the categories and tags of each post.
+func (r *RepoSqlLite) attachPostDetails(posts []domain.Posts) error {
+	err := r.attachCategories(posts)
 	if err != nil {
 		return err
 	}
+	return r.attachTags(posts)
+}
 
-	_, err = r.db.Exec("DELETE FROM likes WHERE post_id = ?", postID)
-	if err != nil {
-		return err
-	}
+// GetPosts returns a page of the posts the viewer may see in the order of
+// filter.Sort.
+func (r *RepoSqlLite) GetPosts(filter domain.PostFilter, viewerID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return r.sortedPostPage(visibleTo, filter, req, viewerID)
+}
 
-	_, err = r.db.Exec("DELETE FROM dislikes WHERE post_id = ?", postID)
-	if err != nil {
+func (r *RepoSqlLite) GetUserPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return r.queryPostPage("SELECT "+postColumns+" FROM posts WHERE user_id = ?", req, userID)
+}
+
+// DeletePost and DeleteComment write entry, unless it is nil, along with
+// the deletion.
+func (r *RepoSqlLite) DeletePost(postID int, entry *domain.AuditEntry) error {
+	return r.audited(entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM posts WHERE post_id = ?", postID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM likes WHERE post_id = ?", postID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM dislikes WHERE post_id = ?", postID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM post_categories WHERE post_id = ?", postID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM post_tags WHERE post_id = ?", postID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM search_alerts WHERE post_id = ?", postID)
 		return err
-	}
-	return nil
+	})
 }
 
 func (r *RepoSqlLite) GetPostByID(postID int) (domain.Posts, error) {
 	var p domain.Posts
-	err := r.db.QueryRow("SELECT post_id, user_id, username, category, title, content, imagefield, creation_date, likes, dislikes FROM posts WHERE post_id = ?", postID).
-		Scan(&p.PostId, &p.UserId, &p.Username, &p.Category, &p.Title, &p.Content, &p.ImageField, &p.CreationDate, &p.Likes, &p.Dislikes)
+	err := r.db.QueryRow("SELECT "+postColumns+", hidden, shadowed FROM posts WHERE post_id = ?", postID).
+		Scan(&p.PostId, &p.UserId, &p.Username, &p.Title, &p.Content, &p.ContentHTML, &p.ImageField, &p.CreationDate, &p.Likes, &p.Dislikes, &p.Hidden, &p.Shadowed)
 	if err != nil {
 
 		if err == sql.ErrNoRows {
-			return domain.Posts{}, fmt.Errorf("post not found")
+			return domain.Posts{}, domain.ErrPostNotFound
 		}
 		return domain.Posts{}, err
 	}
 
-	return p, nil
+	posts := []domain.Posts{p}
+	err = r.attachCategories(posts)
+	if err != nil {
+		return domain.Posts{}, err
+	}
+	err = r.attachTags(posts)
+	return posts[0], err
 }
 
 func (r *RepoSqlLite) AddComment(comments domain.Comments) error {
-	_, err := r.db.Exec("INSERT INTO comments ( post_id, user_id, content, creation_date, username) VALUES (?,?,?,?,?)", comments.PostId, comments.UserId, comments.Content, comments.CreationDate, comments.Username)
+	_, err := r.db.Exec("INSERT INTO comments ( post_id, user_id, content, content_html, content_version, creation_date, username, shadowed) VALUES (?,?,?,?,?,?,?,?)", comments.PostId, comments.UserId, comments.Content, markdown.Render(comments.Content), markdown.Version, comments.CreationDate, comments.Username, comments.Shadowed)
 	if err != nil {
 		fmt.Println(err)
 		return err
 	}
-	return nil
+	return updateCommentCount(r.db, comments.PostId)
 }
 
-func (r *RepoSqlLite) GetComments(postId int) ([]domain.Comments, error) {
+func (r *RepoSqlLite) GetComments(postId int, viewerID int) ([]domain.Comments, error) {
 	var comments []domain.Comments
-	rows, err := r.db.Query("SELECT comment_id, post_id, user_id, content, creation_date, username, likes , dislikes FROM comments WHERE post_id = ?", postId)
+	rows, err := r.db.Query("SELECT comment_id, post_id, user_id, content, content_html, creation_date, username, likes , dislikes FROM comments WHERE post_id = ? AND "+visibleTo, postId, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -240,7 +682,7 @@ func (r *RepoSqlLite) GetComments(postId int) ([]domain.Comments, error) {
 
 	for rows.Next() {
 		var c domain.Comments
-		err := rows.Scan(&c.CommentId, &c.PostId, &c.UserId, &c.Content, &c.CreationDate, &c.Username, &c.Likes, &c.Dislikes)
+		err := rows.Scan(&c.CommentId, &c.PostId, &c.UserId, &c.Content, &c.ContentHTML, &c.CreationDate, &c.Username, &c.Likes, &c.Dislikes)
 		if err != nil {
 			return nil, err
 		}
@@ -255,25 +697,44 @@ func (r *RepoSqlLite) GetComments(postId int) ([]domain.Comments, error) {
 	return comments, nil
 }
 
-func (r *RepoSqlLite) DeleteComment(commentId int) error {
-	_, err := r.db.Exec("DELETE FROM comments WHERE comment_id = ?", commentId)
-	if err != nil {
-		return err
+func (r *RepoSqlLite) GetCommentByID(commentID int) (domain.Comments, error) {
+	var c domain.Comments
+	err := r.db.QueryRow("SELECT comment_id, post_id, user_id, content, content_html, creation_date, username, likes, dislikes, hidden, shadowed FROM comments WHERE comment_id = ?", commentID).
+		Scan(&c.CommentId, &c.PostId, &c.UserId, &c.Content, &c.ContentHTML, &c.CreationDate, &c.Username, &c.Likes, &c.Dislikes, &c.Hidden, &c.Shadowed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Comments{}, domain.ErrCommentNotFound
 	}
-	return nil
+	return c, err
+}
+
+func (r *RepoSqlLite) DeleteComment(commentId int, entry *domain.AuditEntry) error {
+	return r.audited(entry, func(tx *sql.Tx) error {
+		var postID int
+		err := tx.QueryRow("SELECT post_id FROM comments WHERE comment_id = ?", commentId).Scan(&postID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM comments WHERE comment_id = ?", commentId)
+		if err != nil {
+			return err
+		}
+		return updateCommentCount(tx, postID)
+	})
 }
 
 func (r *RepoSqlLite) GetUserById(userId int) ([]domain.User, error) {
 	var users []domain.User
-	rows, err := r.db.Query("SELECT user_id, username, email, password, registration_date FROM users WHERE post_id = ?", userId)
+	rows, err := r.db.Query("SELECT "+userColumns+" FROM users u WHERE u.user_id = ?", userId)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var c domain.User
-		err := rows.Scan(&c.UserId, &c.Username, &c.Email, &c.Password, &c.RegistrationDate)
+		c, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -365,7 +826,7 @@ func (r *RepoSqlLite) LikePost(postID, userID int, notification domain.Notificat
 		}
 	}
 
-	return nil
+	return r.updatePostScores(postID)
 }
 
 func (r *RepoSqlLite) DislikePost(postID, userID int, notification domain.Notification) error {
@@ -429,7 +890,7 @@ func (r *RepoSqlLite) DislikePost(postID, userID int, notification domain.Notifi
 		}
 	}
 
-	return nil
+	return r.updatePostScores(postID)
 }
 
 func (r *RepoSqlLite) HasLikedPost(postID, userID int) (bool, error) {
@@ -468,70 +929,15 @@ func (r *RepoSqlLite) GetDislikesCount(postID int) (int, error) {
 	return count, nil
 }
 
-func (r *RepoSqlLite) GetLikedPostIDs(userID int) ([]int, error) {
-	var likedPostIDs []int
-
-	rows, err := r.db.Query("SELECT post_id FROM likes WHERE user_id = ?", userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var postID int
-
-		err := rows.Scan(&postID)
-		if err != nil {
-			return nil, err
-		}
-		likedPostIDs = append(likedPostIDs, postID)
-	}
-	return likedPostIDs, nil
+// GetLikedPosts returns a page of the posts the user liked that they may see.
+func (r *RepoSqlLite) GetLikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return r.queryPostPage("SELECT "+postColumns+" FROM posts WHERE "+visibleTo+" AND post_id IN (SELECT post_id FROM likes WHERE user_id = ?)", req, userID, userID)
 }
 
-func (r *RepoSqlLite) GetDislikedPostIDs(userID int) ([]int, error) {
-	var dislikedPostIDs []int
-
-	rows, err := r.db.Query("SELECT post_id FROM dislikes WHERE user_id = ?", userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var postID int
-
-		err := rows.Scan(&postID)
-		if err != nil {
-			return nil, err
-		}
-		dislikedPostIDs = append(dislikedPostIDs, postID)
-	}
-	return dislikedPostIDs, nil
-}
-
-func (r *RepoSqlLite) GetPostsByCategories(category []string) ([]domain.Posts, error) {
-	var posts []domain.Posts
-
-	for _, c := range category {
-		rows, err := r.db.Query("SELECT post_id, user_id, username, category, title, content, imagefield, creation_date, likes, dislikes FROM posts WHERE category = ?", c)
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var post domain.Posts
-			err := rows.Scan(&post.PostId, &post.UserId, &post.Username, &post.Category, &post.Title, &post.Content, &post.ImageField, &post.CreationDate, &post.Likes, &post.Dislikes)
-			if err != nil {
-				return nil, err
-			}
-
-			posts = append(posts, post)
-		}
-	}
-
-	return posts, nil
+// GetDislikedPosts returns a page of the posts the user disliked that they
+// may see.
+func (r *RepoSqlLite) GetDislikedPosts(userID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return r.queryPostPage("SELECT "+postColumns+" FROM posts WHERE "+visibleTo+" AND post_id IN (SELECT post_id FROM dislikes WHERE user_id = ?)", req, userID, userID)
 }
 
 func (r *RepoSqlLite) LikeComment(commentID int, userID int, notification_comments domain.Notification_comments) error {
@@ -706,61 +1112,41 @@ func (r *RepoSqlLite) GetLikesCountForComment(commentID int) (int, error) {
 	return count, nil
 }
 
-func (r *RepoSqlLite) InvalidateSessions(userID int) error {
-	_, err := r.db.Exec("DELETE FROM session WHERE user_id = ?", userID)
-	return err
+// InvalidateSessions ends every session of a user, writing entry when an
+// administrator does it.
+func (r *RepoSqlLite) InvalidateSessions(userID int, entry *domain.AuditEntry) error {
+	return r.audited(entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM session WHERE user_id = ?", userID)
+		return err
+	})
 }
 
-// GetCommentsByUser retrieves comments left by a user.
-func (r *RepoSqlLite) GetCommentsByUser(userID int) ([]domain.Comments, error) {
+// GetCommentsByUser retrieves a page of the comments left by a user.
+func (r *RepoSqlLite) GetCommentsByUser(userID int, req domain.PageRequest) ([]domain.Comments, domain.Page, error) {
 	comments := []domain.Comments{}
-	query := "SELECT comment_id, post_id, user_id, content, username FROM comments WHERE user_id = ?"
+	clause, pageArgs := pageClause("comment_id", req)
+	query := "SELECT comment_id, post_id, user_id, content, username FROM comments WHERE user_id = ?" + clause
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, append([]any{userID}, pageArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var comment domain.Comments
 		if err := rows.Scan(&comment.CommentId, &comment.PostId, &comment.UserId, &comment.Content, &comment.Username); err != nil {
-			return nil, err
+			return nil, domain.Page{}, err
 		}
 		comments = append(comments, comment)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 
-	return comments, nil
-}
-
-// GetCreatedPosts retrieves posts created by a user.
-func (r *RepoSqlLite) GetCreatedPosts(userID int) ([]domain.Posts, error) {
-	posts := []domain.Posts{}
-	query := "SELECT post_id, title, content, user_id, creation_date, category FROM posts WHERE user_id = ?"
-
-	rows, err := r.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var post domain.Posts
-		if err := rows.Scan(&post.PostId, &post.Title, &post.Content, &post.UserId, &post.CreationDate, &post.Category); err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return posts, nil
+	comments, page := pageOf(comments, req, func(c domain.Comments) domain.Cursor { return domain.Cursor{Id: c.CommentId} })
+	return comments, page, nil
 }
 
 func (r *RepoSqlLite) CreateNotification(notification domain.Notification) error {
@@ -775,51 +1161,134 @@ func (r *RepoSqlLite) CreateNotificationComments(notification domain.Notificatio
 	return err
 }
 
-// GetAllNotifications retrieves general notifications for an owner.
-func (r *RepoSqlLite) GetAllNotifications(ownerID int) ([]domain.Notification, error) {
+// GetAllNotifications retrieves a page of general notifications for an owner.
+func (r *RepoSqlLite) GetAllNotifications(ownerID int, req domain.PageRequest) ([]domain.Notification, domain.Page, error) {
 	notifications := []domain.Notification{}
-	query := "SELECT id, user_id, activity, post_id, owner_id, creation_date,username FROM notifications WHERE owner_id = ?"
-	rows, err := r.db.Query(query, ownerID)
+	clause, pageArgs := pageClause("id", req)
+	query := "SELECT id, user_id, activity, post_id, owner_id, creation_date,username FROM notifications WHERE owner_id = ?" + clause
+	rows, err := r.db.Query(query, append([]any{ownerID}, pageArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var notification domain.Notification
 		if err := rows.Scan(&notification.Id, &notification.UserId, &notification.Type, &notification.PostId, &notification.OwnerId, &notification.Timestamp, &notification.Username); err != nil {
-			return nil, err
+			return nil, domain.Page{}, err
 		}
 		notifications = append(notifications, notification)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 
-	return notifications, nil
+	notifications, page := pageOf(notifications, req, func(n domain.Notification) domain.Cursor { return domain.Cursor{Id: n.Id} })
+	return notifications, page, nil
 }
 
-// GetAllNotificationsComment retrieves comment-specific notifications for an owner.
-func (r *RepoSqlLite) GetAllNotificationsComment(ownerID int) ([]domain.Notification_comments, error) {
+// GetAllNotificationsComment retrieves a page of comment-specific
+// notifications for an owner.
+func (r *RepoSqlLite) GetAllNotificationsComment(ownerID int, req domain.PageRequest) ([]domain.Notification_comments, domain.Page, error) {
 	notifications := []domain.Notification_comments{}
-	query := "SELECT id, user_id, activity, comment_id, owner_id, creation_date,username , post_id FROM notifications_comments WHERE owner_id = ?"
-	rows, err := r.db.Query(query, ownerID)
+	clause, pageArgs := pageClause("id", req)
+	query := "SELECT id, user_id, activity, comment_id, owner_id, creation_date,username , post_id FROM notifications_comments WHERE owner_id = ?" + clause
+	rows, err := r.db.Query(query, append([]any{ownerID}, pageArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var notification domain.Notification_comments
 		if err := rows.Scan(&notification.Id, &notification.UserId, &notification.Type, &notification.CommentId, &notification.OwnerId, &notification.Timestamp, &notification.Username, &notification.PostId); err != nil {
-			return nil, err
+			return nil, domain.Page{}, err
 		}
 		notifications = append(notifications, notification)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, domain.Page{}, err
 	}
 
-	return notifications, nil
+	notifications, page := pageOf(notifications, req, func(n domain.Notification_comments) domain.Cursor { return domain.Cursor{Id: n.Id} })
+	return notifications, page, nil
+}
+
+// UpdatePassword sets the password of a user, writing entry when an
+// administrator does it.
+func (r *RepoSqlLite) UpdatePassword(userID int, password string, entry *domain.AuditEntry) error {
+	return r.audited(entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE users SET password = ? WHERE user_id = ?", password, userID)
+		return err
+	})
+}
+
+func (r *RepoSqlLite) SavePasswordReset(reset domain.PasswordReset) error {
+	_, err := r.db.Exec("INSERT INTO password_resets (user_id, token_hash, creation_date, expiration_date) VALUES (?,?,?,?)", reset.UserId, reset.TokenHash, reset.CreationDate, reset.ExpirationDate)
+	return err
+}
+
+func (r *RepoSqlLite) GetPasswordReset(tokenHash string) (domain.PasswordReset, error) {
+	var reset domain.PasswordReset
+	err := r.db.QueryRow("SELECT id, user_id, token_hash, creation_date, expiration_date FROM password_resets WHERE token_hash = ?", tokenHash).
+		Scan(&reset.Id, &reset.UserId, &reset.TokenHash, &reset.CreationDate, &reset.ExpirationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.PasswordReset{}, domain.ErrInvalidResetToken
+	}
+	return reset, err
+}
+
+// ConsumePasswordReset deletes the token so it cannot be used twice. It fails
+// with ErrInvalidResetToken if another request already consumed it.
+func (r *RepoSqlLite) ConsumePasswordReset(id int) error {
+	res, err := r.db.Exec("DELETE FROM password_resets WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrInvalidResetToken
+	}
+	return nil
+}
+
+func (r *RepoSqlLite) DeletePasswordResets(userID int) error {
+	_, err := r.db.Exec("DELETE FROM password_resets WHERE user_id = ?", userID)
+	return err
+}
+
+func (r *RepoSqlLite) SetUserRole(userID int, role domain.Role, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE users SET role = ? WHERE user_id = ?", role, userID)
+		return err
+	})
+}
+
+func (r *RepoSqlLite) SetEmailVerified(userID int) error {
+	_, err := r.db.Exec("UPDATE users SET email_verified = 1 WHERE user_id = ?", userID)
+	return err
+}
+
+func (r *RepoSqlLite) SaveEmailVerification(verification domain.EmailVerification) error {
+	_, err := r.db.Exec("INSERT INTO email_verifications (user_id, token_hash, creation_date, expiration_date) VALUES (?,?,?,?)", verification.UserId, verification.TokenHash, verification.CreationDate, verification.ExpirationDate)
+	return err
+}
+
+func (r *RepoSqlLite) GetEmailVerification(tokenHash string) (domain.EmailVerification, error) {
+	var verification domain.EmailVerification
+	err := r.db.QueryRow("SELECT id, user_id, token_hash, creation_date, expiration_date FROM email_verifications WHERE token_hash = ?", tokenHash).
+		Scan(&verification.Id, &verification.UserId, &verification.TokenHash, &verification.CreationDate, &verification.ExpirationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.EmailVerification{}, domain.ErrInvalidVerifyToken
+	}
+	return verification, err
+}
+
+func (r *RepoSqlLite) DeleteEmailVerifications(userID int) error {
+	_, err := r.db.Exec("DELETE FROM email_verifications WHERE user_id = ?", userID)
+	return err
 }
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"database/sql"
+
+	"forum/forum/domain"
+)
+
+// setPostTags replaces the tags of a post, creating tags used for the first
+// time.
+func setPostTags(tx *sql.Tx, postID int, tags []string) error {
+	_, err := tx.Exec("DELETE FROM post_tags WHERE post_id = ?", postID)
+	if err != nil {
+		return err
+	}
+	for _, name := range tags {
+		_, err = tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT OR IGNORE INTO post_tags (post_id, tag_id) SELECT ?, tag_id FROM tags WHERE name = ?", postID, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTags fills in the tags of each post, leaving out banned ones.
+func (r *RepoSqlLite) attachTags(posts []domain.Posts) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	index := make(map[int][]int)
+	args := make([]any, 0, len(posts))
+	for i, p := range posts {
+		if _, ok := index[p.PostId]; !ok {
+			args = append(args, p.PostId)
+		}
+		index[p.PostId] = append(index[p.PostId], i)
+	}
+
+	rows, err := r.db.Query(`SELECT pt.post_id, t.name FROM post_tags pt
+		JOIN tags t ON t.tag_id = pt.tag_id
+		WHERE t.banned = 0 AND pt.post_id IN (`+placeholders(len(args))+`)
+		ORDER BY t.name`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		err = rows.Scan(&postID, &name)
+		if err != nil {
+			return err
+		}
+		for _, i := range index[postID] {
+			posts[i].Tags = append(posts[i].Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+func (r *RepoSqlLite) GetTag(name string) (domain.Tag, error) {
+	var t domain.Tag
+	err := r.db.QueryRow("SELECT tag_id, name, banned FROM tags WHERE name = ?", name).Scan(&t.Id, &t.Name, &t.Banned)
+	if err == sql.ErrNoRows {
+		return domain.Tag{}, domain.ErrTagNotFound
+	}
+	return t, err
+}
+
+// GetTags returns every tag, banned ones included, by name, each with the
+// number of posts the viewer may see under it.
+func (r *RepoSqlLite) GetTags(viewerID int) ([]domain.Tag, error) {
+	rows, err := r.db.Query(`SELECT t.tag_id, t.name, t.banned, COUNT(p.post_id) FROM tags t
+		LEFT JOIN post_tags pt ON pt.tag_id = t.tag_id
+		LEFT JOIN posts p ON p.post_id = pt.post_id AND p.hidden = 0 AND (p.shadowed = 0 OR p.user_id = ?)
+		GROUP BY t.tag_id
+		ORDER BY t.name`, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []domain.Tag
+	for rows.Next() {
+		var t domain.Tag
+		err = rows.Scan(&t.Id, &t.Name, &t.Banned, &t.PostCount)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// SearchTags returns up to limit names of tags starting with prefix, most
+// used first. Banned tags are left out.
+func (r *RepoSqlLite) SearchTags(prefix string, limit int) ([]string, error) {
+	rows, err := r.db.Query(`SELECT t.name FROM tags t
+		LEFT JOIN post_tags pt ON pt.tag_id = t.tag_id
+		WHERE t.banned = 0 AND substr(t.name, 1, length(?)) = ?
+		GROUP BY t.tag_id
+		ORDER BY COUNT(pt.post_id) DESC, t.name
+		LIMIT ?`, prefix, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// GetPostsByTag returns a page of the posts the viewer may see that carry
+// the tag.
+func (r *RepoSqlLite) GetPostsByTag(tagID int, viewerID int, req domain.PageRequest) ([]domain.Posts, domain.Page, error) {
+	return r.queryPostPage("SELECT "+postColumns+" FROM posts WHERE "+visibleTo+" AND post_id IN (SELECT post_id FROM post_tags WHERE tag_id = ?)", req, viewerID, tagID)
+}
+
+func (r *RepoSqlLite) SetTagBanned(tagID int, banned bool, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE tags SET banned = ? WHERE tag_id = ?", banned, tagID)
+		return err
+	})
+}
+
+func (r *RepoSqlLite) RenameTag(tagID int, name string, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE tags SET name = ? WHERE tag_id = ?", name, tagID)
+		return err
+	})
+}
+
+// MergeTags moves the posts tagged fromID to intoID and deletes fromID.
+func (r *RepoSqlLite) MergeTags(fromID, intoID int, entry domain.AuditEntry) error {
+	return r.audited(&entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT OR IGNORE INTO post_tags (post_id, tag_id) SELECT post_id, ? FROM post_tags WHERE tag_id = ?", intoID, fromID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", fromID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM tags WHERE tag_id = ?", fromID)
+		return err
+	})
+}
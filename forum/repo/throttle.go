@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/forum/domain"
+)
+
+// GetThrottle returns an empty throttle for keys without recent events.
+func (r *RepoSqlLite) GetThrottle(key string) (domain.Throttle, error) {
+	throttle := domain.Throttle{Key: key}
+	var blockedUntil sql.NullTime
+	err := r.db.QueryRow("SELECT count, last_event, blocked_until FROM auth_throttles WHERE key = ?", key).
+		Scan(&throttle.Count, &throttle.LastEvent, &blockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return throttle, nil
+	}
+	if err != nil {
+		return domain.Throttle{}, err
+	}
+	throttle.BlockedUntil = blockedUntil.Time
+	return throttle, nil
+}
+
+// CountThrottleEvent records an event and returns how many there have been.
+// The count starts over when the previous event happened before resetBefore.
+// It is a single statement so concurrent requests cannot lose an update.
+func (r *RepoSqlLite) CountThrottleEvent(key string, now, resetBefore time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		INSERT INTO auth_throttles (key, count, last_event) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN julianday(last_event) < julianday(?) THEN 1 ELSE count + 1 END,
+			last_event = excluded.last_event
+		RETURNING count`, key, now, resetBefore).Scan(&count)
+	return count, err
+}
+
+func (r *RepoSqlLite) BlockThrottle(key string, until time.Time) error {
+	_, err := r.db.Exec("UPDATE auth_throttles SET blocked_until = ? WHERE key = ?", until, key)
+	return err
+}
+
+func (r *RepoSqlLite) DeleteThrottle(key string) error {
+	_, err := r.db.Exec("DELETE FROM auth_throttles WHERE key = ?", key)
+	return err
+}
+
+// DeleteStaleThrottles removes counters whose last event is older than before
+// and that no longer block anything.
+func (r *RepoSqlLite) DeleteStaleThrottles(before time.Time) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM auth_throttles WHERE julianday(last_event) < julianday(?) AND (blocked_until IS NULL OR julianday(blocked_until) < julianday(?))", before, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+
+	"forum/forum/domain"
+)
+
+func (r *RepoSqlLite) GetTwoFactor(userID int) (domain.TwoFactor, error) {
+	var tf domain.TwoFactor
+	err := r.db.QueryRow("SELECT user_id, secret, enabled, last_counter, creation_date FROM user_totp WHERE user_id = ?", userID).
+		Scan(&tf.UserId, &tf.Secret, &tf.Enabled, &tf.LastCounter, &tf.CreationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.TwoFactor{}, domain.ErrTwoFactorNotEnabled
+	}
+	return tf, err
+}
+
+func (r *RepoSqlLite) SaveTwoFactor(tf domain.TwoFactor) error {
+	_, err := r.db.Exec("INSERT OR REPLACE INTO user_totp (user_id, secret, enabled, last_counter, creation_date) VALUES (?,?,?,?,?)", tf.UserId, tf.Secret, tf.Enabled, tf.LastCounter, tf.CreationDate)
+	return err
+}
+
+// UseTwoFactorCounter records the time step of an accepted code. It fails
+// with ErrInvalidTwoFactorCode if that step or a later one was already used,
+// which stops a code from being replayed.
+func (r *RepoSqlLite) UseTwoFactorCounter(userID int, counter int64) error {
+	res, err := r.db.Exec("UPDATE user_totp SET last_counter = ? WHERE user_id = ? AND last_counter < ?", counter, userID, counter)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// DeleteTwoFactor removes the TOTP enrollment and recovery codes of a user,
+// writing entry when an administrator does it.
+func (r *RepoSqlLite) DeleteTwoFactor(userID int, entry *domain.AuditEntry) error {
+	return r.audited(entry, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM user_totp WHERE user_id = ?", userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+// SaveRecoveryCodes replaces the recovery codes of a user.
+func (r *RepoSqlLite) SaveRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?,?)", userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode deletes a matching recovery code so it works only once.
+func (r *RepoSqlLite) UseRecoveryCode(userID int, codeHash string) error {
+	res, err := r.db.Exec("DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?", userID, codeHash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (r *RepoSqlLite) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = ?", userID).Scan(&count)
+	return count, err
+}
+
+func (r *RepoSqlLite) SaveLoginChallenge(challenge domain.LoginChallenge) error {
+	_, err := r.db.Exec("INSERT INTO login_challenges (user_id, token_hash, attempts, expiration_date) VALUES (?,?,?,?)", challenge.UserId, challenge.TokenHash, challenge.Attempts, challenge.ExpirationDate)
+	return err
+}
+
+func (r *RepoSqlLite) GetLoginChallenge(tokenHash string) (domain.LoginChallenge, error) {
+	var challenge domain.LoginChallenge
+	err := r.db.QueryRow("SELECT id, user_id, token_hash, attempts, expiration_date FROM login_challenges WHERE token_hash = ?", tokenHash).
+		Scan(&challenge.Id, &challenge.UserId, &challenge.TokenHash, &challenge.Attempts, &challenge.ExpirationDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.LoginChallenge{}, domain.ErrInvalidLoginChallenge
+	}
+	return challenge, err
+}
+
+func (r *RepoSqlLite) CountLoginChallengeAttempt(id int) error {
+	_, err := r.db.Exec("UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ?", id)
+	return err
+}
+
+func (r *RepoSqlLite) DeleteLoginChallenge(id int) error {
+	_, err := r.db.Exec("DELETE FROM login_challenges WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,238 @@
+// Package searchquery parses the search query language of the forum: words
+// and "quoted phrases" to look for, and operators limiting the results:
+//
+//	author:alice          written by alice
+//	category:horror       in the category with that slug or name
+//	tag:go                carrying the tag
+//	before:2026-01-01     written before that day
+//	after:2026-01-01      written on that day or later
+//	likes:>10             with more than 10 likes; also >=, <, <= and likes:10
+//	has:image             with an image
+//	is:unanswered         without comments
+//
+// Operator values may be quoted too, as in category:"science fiction". A
+// word ending in * matches every word starting with it.
+package searchquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"forum/forum/domain"
+)
+
+const dateLayout = "2006-01-02"
+
+// Error is a query that could not be parsed. Pos is the byte offset in the
+// query of what is wrong. It matches domain.ErrInvalidSearch with errors.Is.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func (e *Error) Is(target error) bool {
+	return target == domain.ErrInvalidSearch
+}
+
+// Parse parses a query into the filter it describes.
+func Parse(query string) (domain.SearchFilter, error) {
+	p := parser{query: query}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.query) {
+			break
+		}
+		err := p.parseToken()
+		if err != nil {
+			return domain.SearchFilter{}, err
+		}
+	}
+	if !p.filter.Before.IsZero() && !p.filter.After.IsZero() && !p.filter.After.Before(p.filter.Before) {
+		return domain.SearchFilter{}, &Error{p.afterPos, "after: must be earlier than before:"}
+	}
+	return p.filter, nil
+}
+
+type parser struct {
+	query    string
+	pos      int
+	filter   domain.SearchFilter
+	afterPos int
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.query) {
+		r, size := utf8.DecodeRuneInString(p.query[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += size
+	}
+}
+
+// parseToken parses the phrase, operator or word at the current position.
+func (p *parser) parseToken() error {
+	start := p.pos
+	if p.query[p.pos] == '"' {
+		text, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		if words := strings.Fields(text); len(words) > 0 {
+			p.filter.Terms = append(p.filter.Terms, domain.SearchTerm{Text: strings.Join(words, " "), Phrase: true})
+		}
+		return nil
+	}
+
+	if key, ok := p.operatorKey(); ok {
+		p.pos += len(key) + 1
+		key = strings.ToLower(key)
+		if _, known := examples[key]; !known {
+			return &Error{start, fmt.Sprintf("%s: is not a search operator, use author:, category:, tag:, before:, after:, likes:, has: or is:", key)}
+		}
+		var value string
+		var err error
+		if p.pos < len(p.query) && p.query[p.pos] == '"' {
+			value, err = p.quoted()
+			if err != nil {
+				return err
+			}
+			value = strings.TrimSpace(value)
+		} else {
+			value = p.word()
+		}
+		if value == "" {
+			return &Error{start, fmt.Sprintf("%s: needs a value, like %s", key, examples[key])}
+		}
+		return p.operator(key, value, start)
+	}
+
+	word := p.word()
+	term := domain.SearchTerm{Text: strings.TrimRight(word, "*")}
+	term.Prefix = term.Text != word
+	if term.Text != "" {
+		p.filter.Terms = append(p.filter.Terms, term)
+	}
+	return nil
+}
+
+// quoted reads the text between the quote at the current position and the
+// next one.
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	end := strings.IndexByte(p.query[start+1:], '"')
+	if end < 0 {
+		return "", &Error{start, "a quote is not closed"}
+	}
+	p.pos = start + 1 + end + 1
+	return p.query[start+1 : start+1+end], nil
+}
+
+// word reads up to the next space.
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.query) {
+		r, size := utf8.DecodeRuneInString(p.query[p.pos:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		p.pos += size
+	}
+	return p.query[start:p.pos]
+}
+
+// operatorKey returns the name of the operator at the current position: the
+// letters before a colon. Words with other characters before their colon,
+// such as 10:30, are not operators.
+func (p *parser) operatorKey() (string, bool) {
+	rest := p.query[p.pos:]
+	i := 0
+	for i < len(rest) && ('a' <= rest[i] && rest[i] <= 'z' || 'A' <= rest[i] && rest[i] <= 'Z') {
+		i++
+	}
+	return rest[:i], i > 0 && i < len(rest) && rest[i] == ':'
+}
+
+var examples = map[string]string{
+	"author":   "author:alice",
+	"category": "category:horror",
+	"tag":      "tag:go",
+	"before":   "before:2026-01-01",
+	"after":    "after:2026-01-01",
+	"likes":    "likes:>10",
+	"has":      "has:image",
+	"is":       "is:unanswered",
+}
+
+// operator adds the operator key, one of examples, with value, which
+// started at pos, to the filter.
+func (p *parser) operator(key, value string, pos int) error {
+	switch key {
+	case "author":
+		if p.filter.Author != "" && !strings.EqualFold(p.filter.Author, value) {
+			return &Error{pos, "a post has only one author, use author: once"}
+		}
+		p.filter.Author = value
+	case "category":
+		p.filter.Categories = append(p.filter.Categories, strings.ToLower(value))
+	case "tag":
+		p.filter.Tags = append(p.filter.Tags, value)
+	case "before", "after":
+		day, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return &Error{pos, fmt.Sprintf("%s: needs a date written year-month-day, like %s", key, examples[key])}
+		}
+		if key == "before" {
+			p.filter.Before = day
+		} else {
+			p.filter.After = day
+			p.afterPos = pos
+		}
+	case "likes":
+		comparison, ok := parseComparison(value)
+		if !ok {
+			return &Error{pos, "likes: needs a number, optionally after >, >=, < or <=, like likes:>10"}
+		}
+		p.filter.Likes = append(p.filter.Likes, comparison)
+	case "has":
+		if strings.ToLower(value) != "image" {
+			return &Error{pos, fmt.Sprintf("has:%s is not known, use has:image", value)}
+		}
+		p.filter.HasImage = true
+	case "is":
+		if strings.ToLower(value) != "unanswered" {
+			return &Error{pos, fmt.Sprintf("is:%s is not known, use is:unanswered", value)}
+		}
+		p.filter.Unanswered = true
+	}
+	return nil
+}
+
+// comparisonOps lists the operators of a comparison, longest first so that
+// >= is not read as >.
+var comparisonOps = []domain.CompareOp{domain.OpGreaterEqual, domain.OpLessEqual, domain.OpGreater, domain.OpLess, domain.OpEqual}
+
+func parseComparison(value string) (domain.Comparison, bool) {
+	c := domain.Comparison{Op: domain.OpEqual}
+	for _, op := range comparisonOps {
+		if strings.HasPrefix(value, string(op)) {
+			c.Op = op
+			value = value[len(op):]
+			break
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return domain.Comparison{}, false
+	}
+	c.Value = n
+	return c, true
+}
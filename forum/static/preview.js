@@ -0,0 +1,33 @@
+// Markdown preview for the post and comment forms. A button with
+// data-preview="<textarea id>" shows what the text will look like in the
+// element with the id "<textarea id>-preview". The server renders and
+// sanitizes the HTML, so it is shown as it comes.
+
+document.querySelectorAll("button[data-preview]").forEach((button) => {
+  const source = document.getElementById(button.dataset.preview);
+  const target = document.getElementById(button.dataset.preview + "-preview");
+  if (!source || !target) {
+    return;
+  }
+  button.addEventListener("click", async () => {
+    const csrf = document.querySelector('meta[name="csrf-token"]');
+    try {
+      const response = await fetch("/preview", {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          "X-CSRF-Token": csrf ? csrf.content : "",
+        },
+        body: JSON.stringify({ text: source.value }),
+      });
+      const data = await response.json();
+      if (!response.ok) {
+        throw new Error(data.error || "Preview failed");
+      }
+      target.innerHTML = data.html;
+    } catch (err) {
+      target.textContent = err.message;
+    }
+    target.hidden = false;
+  });
+});
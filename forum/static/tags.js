@@ -0,0 +1,31 @@
+// Tag autocomplete for the post forms. The tags field holds comma separated
+// tags; suggestions for the one being typed come from /tags/suggest and are
+// offered as whole field values, so picking one keeps the tags before it.
+
+const tagsInput = document.getElementById("tags");
+const tagSuggestions = document.getElementById("tag-suggestions");
+let tagRequest = 0;
+
+if (tagsInput && tagSuggestions) {
+  tagsInput.addEventListener("input", async () => {
+    const parts = tagsInput.value.split(",");
+    const typed = parts.pop().trim();
+    const before = parts.map((part) => part.trim()).filter((part) => part !== "");
+    const request = ++tagRequest;
+    if (typed === "") {
+      tagSuggestions.replaceChildren();
+      return;
+    }
+
+    const response = await fetch("/tags/suggest?q=" + encodeURIComponent(typed));
+    if (!response.ok || request !== tagRequest) {
+      return;
+    }
+    const names = await response.json();
+    tagSuggestions.replaceChildren(...names.map((name) => {
+      const option = document.createElement("option");
+      option.value = before.concat(name).join(", ");
+      return option;
+    }));
+  });
+}
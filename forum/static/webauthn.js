@@ -0,0 +1,113 @@
+// Passkey registration and sign-in. The server sends WebAuthn options with
+// binary fields as base64url strings; they are converted to ArrayBuffers for
+// the browser and back again for the response.
+
+function base64urlToBuffer(value) {
+  const base64 = value.replace(/-/g, "+").replace(/_/g, "/");
+  const padded = base64 + "===".slice((base64.length + 3) % 4);
+  const binary = atob(padded);
+  const bytes = new Uint8Array(binary.length);
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i);
+  }
+  return bytes.buffer;
+}
+
+function bufferToBase64url(buffer) {
+  const bytes = new Uint8Array(buffer);
+  let binary = "";
+  for (let i = 0; i < bytes.length; i++) {
+    binary += String.fromCharCode(bytes[i]);
+  }
+  return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+async function postJSON(url, body) {
+  const csrf = document.querySelector('meta[name="csrf-token"]');
+  const response = await fetch(url, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+      "X-CSRF-Token": csrf ? csrf.content : "",
+    },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.error || "Request failed");
+  }
+  return data;
+}
+
+function showPasskeyError(message) {
+  const box = document.getElementById("passkey-error");
+  if (box) {
+    box.textContent = message;
+  }
+}
+
+async function registerPasskey() {
+  const { publicKey } = await postJSON("/webauthn/register/begin");
+  publicKey.challenge = base64urlToBuffer(publicKey.challenge);
+  publicKey.user.id = base64urlToBuffer(publicKey.user.id);
+  publicKey.excludeCredentials = publicKey.excludeCredentials.map((c) => ({
+    type: c.type,
+    id: base64urlToBuffer(c.id),
+  }));
+
+  const credential = await navigator.credentials.create({ publicKey });
+  const nameInput = document.getElementById("passkey-name");
+  const result = await postJSON("/webauthn/register/finish", {
+    name: nameInput ? nameInput.value : "",
+    credential: {
+      id: credential.id,
+      rawId: bufferToBase64url(credential.rawId),
+      type: credential.type,
+      response: {
+        clientDataJSON: bufferToBase64url(credential.response.clientDataJSON),
+        attestationObject: bufferToBase64url(credential.response.attestationObject),
+      },
+    },
+  });
+  window.location = result.redirect;
+}
+
+async function loginWithPasskey() {
+  const { publicKey } = await postJSON("/webauthn/login/begin");
+  publicKey.challenge = base64urlToBuffer(publicKey.challenge);
+
+  const credential = await navigator.credentials.get({ publicKey });
+  const result = await postJSON("/webauthn/login/finish", {
+    id: credential.id,
+    rawId: bufferToBase64url(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: bufferToBase64url(credential.response.clientDataJSON),
+      authenticatorData: bufferToBase64url(credential.response.authenticatorData),
+      signature: bufferToBase64url(credential.response.signature),
+      userHandle: credential.response.userHandle
+        ? bufferToBase64url(credential.response.userHandle)
+        : "",
+    },
+  });
+  window.location = result.redirect;
+}
+
+function bindPasskeyButton(id, action) {
+  const button = document.getElementById(id);
+  if (!button) {
+    return;
+  }
+  if (!window.PublicKeyCredential) {
+    button.disabled = true;
+    showPasskeyError("This browser does not support passkeys.");
+    return;
+  }
+  button.addEventListener("click", () => {
+    showPasskeyError("");
+    action().catch((err) => showPasskeyError(err.message));
+  });
+}
+
+bindPasskeyButton("passkey-register", registerPasskey);
+bindPasskeyButton("passkey-login", loginWithPasskey);
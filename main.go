@@ -1,32 +1,121 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 
+	"forum/forum"
 	"forum/forum/business"
+	"forum/forum/domain"
 	"forum/forum/handlers"
+	"forum/forum/mailer"
+	"forum/forum/markdown"
 	"forum/forum/middleware"
+	"forum/forum/oauth"
+	"forum/forum/oauth/mockidp"
 	"forum/forum/repo"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
+	var err error
 	var port int
+	var smtpAddr, smtpUser, mailFrom, mailFile, unverified, grantAdmin, oauthProviders string
+	var mockIdP, rebuildSearch, highlightCSS bool
+	config := business.DefaultConfig()
 	flag.IntVar(&port, "port", 8080, "Port to listen on")
+	flag.StringVar(&config.BaseURL, "base-url", config.BaseURL, "Public URL of the forum used in emailed links and as the passkey origin")
+	flag.DurationVar(&config.SessionIdleTimeout, "session-idle", config.SessionIdleTimeout, "Session lifetime without activity")
+	flag.DurationVar(&config.SessionMaxLifetime, "session-max", config.SessionMaxLifetime, "Absolute session lifetime")
+	flag.DurationVar(&config.SessionReapInterval, "session-reap", config.SessionReapInterval, "Interval between expired session purges")
+	flag.DurationVar(&config.SearchAlertInterval, "search-alerts", config.SearchAlertInterval, "Interval between matches of saved searches against new posts")
+	flag.DurationVar(&config.SearchDigestInterval, "search-digest", config.SearchDigestInterval, "Interval between emailed digests of posts matching saved searches")
+	flag.DurationVar(&config.PasswordResetTTL, "reset-ttl", config.PasswordResetTTL, "Lifetime of password reset links")
+	flag.DurationVar(&config.EmailVerificationTTL, "verify-ttl", config.EmailVerificationTTL, "Lifetime of email verification links")
+	flag.IntVar(&config.LockoutThreshold, "lockout-after", config.LockoutThreshold, "Failed logins after which a username is locked and its owner notified")
+	flag.DurationVar(&config.LockoutDuration, "lockout", config.LockoutDuration, "How long a locked username stays locked")
+	flag.IntVar(&config.RegistrationsPerIP, "register-limit", config.RegistrationsPerIP, "Accounts that may be registered from one IP address per hour, 0 for no limit")
+	flag.StringVar(&unverified, "unverified-may", "", "Comma separated actions (post, comment, react) allowed before email verification")
+	flag.StringVar(&smtpAddr, "smtp-addr", "", "SMTP relay host:port; mail is written to -mail-file when empty")
+	flag.StringVar(&smtpUser, "smtp-user", "", "SMTP username, the password is read from FORUM_SMTP_PASSWORD")
+	flag.StringVar(&mailFrom, "mail-from", "forum@localhost", "Sender address of outgoing mail")
+	flag.StringVar(&mailFile, "mail-file", "", "File receiving outgoing mail when no SMTP relay is set, stdout when empty")
+	flag.StringVar(&grantAdmin, "grant-admin", "", "Give the admin role to this user on startup")
+	flag.StringVar(&oauthProviders, "oauth-providers", "", "JSON file describing the external sign-in providers")
+	flag.BoolVar(&mockIdP, "mock-idp", false, "Serve a mock OpenID Connect provider under /mock-idp/ for development")
+	flag.BoolVar(&rebuildSearch, "rebuild-search", false, "Rebuild the full-text search index of posts and comments, then exit")
+	flag.BoolVar(&highlightCSS, "highlight-css", false, "Write the stylesheet of highlighted code, kept in forum/static/highlight.css, to stdout and exit")
 	flag.Parse()
-	lg := LoggingMiddleware(*log.Default())
+	if highlightCSS {
+		err = markdown.WriteStylesheet(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	config.UnverifiedActions, err = parseActions(unverified)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if oauthProviders != "" {
+		config.OAuthProviders, err = oauth.LoadProviders(oauthProviders)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	var idp *mockidp.Server
+	if mockIdP {
+		idp, err = newMockIdP(&config)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	lg := LoggingMiddleware(log.Default())
 	rep, err := repo.NewDatabase()
 	if err != nil {
 		log.Fatal(err)
 	}
-	bus, err := business.NewBusiness(rep)
+	var mail forum.Mailer
+	switch {
+	case smtpAddr != "":
+		mail = mailer.NewSMTPMailer(smtpAddr, mailFrom, smtpUser, os.Getenv("FORUM_SMTP_PASSWORD"))
+	case mailFile != "":
+		mail, err = mailer.NewFileMailer(mailFile, mailFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		mail = mailer.NewWriterMailer(os.Stdout, mailFrom)
+	}
+	bus, err := business.NewBusiness(rep, mail, config)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if grantAdmin != "" {
+		err = bus.SetUserRole(grantAdmin, domain.RoleAdmin)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if rebuildSearch {
+		err = bus.RebuildSearchIndex()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Search index rebuilt")
+		return
+	}
+	stopReaper := bus.StartSessionReaper()
+	defer stopReaper()
+	stopSearchAlerts := bus.StartSearchAlerts()
+	defer stopSearchAlerts()
 	hand, err := handlers.NewHandler(bus)
 	rateLimiter := middleware.NewRateLimiter(2)
 	mux := http.NewServeMux()
@@ -41,7 +130,14 @@ func main() {
 	})
 	fileServer := http.FileServer(http.Dir("./forum/static"))
 	mux.Handle("/static/", http.StripPrefix("/static", fileServer))
-	mux.Handle("/", customHandler)
+	csrf, err := middleware.NewCSRF(http.HandlerFunc(hand.Handle403))
+	if err != nil {
+		log.Fatal(err)
+	}
+	mux.Handle("/", csrf.Handler(customHandler))
+	if idp != nil {
+		mux.Handle("/mock-idp/", http.StripPrefix("/mock-idp", idp))
+	}
 	addr := fmt.Sprintf(":%d", port)
 
 	fmt.Printf("Server listening on http://localhost%s\n", addr)
@@ -62,7 +158,7 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-func LoggingMiddleware(logger log.Logger) func(http.Handler) http.Handler {
+func LoggingMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			rw := &responseWriter{w, http.StatusOK}
@@ -77,3 +173,42 @@ func LoggingMiddleware(logger log.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+func parseActions(list string) ([]domain.Action, error) {
+	var actions []domain.Action
+	for _, a := range strings.Split(list, ",") {
+		a = strings.TrimSpace(a)
+		switch domain.Action(a) {
+		case "":
+		case domain.ActionPost, domain.ActionComment, domain.ActionReact:
+			actions = append(actions, domain.Action(a))
+		default:
+			return nil, fmt.Errorf("unknown action %q", a)
+		}
+	}
+	return actions, nil
+}
+
+// newMockIdP creates the development identity provider and registers it as
+// the "mock" sign-in provider.
+func newMockIdP(config *business.Config) (*mockidp.Server, error) {
+	secret := make([]byte, 16)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+	idp := mockidp.New(strings.TrimRight(config.BaseURL, "/")+"/mock-idp", "forum", hex.EncodeToString(secret))
+	provider, err := oauth.NewProvider(oauth.ProviderConfig{
+		Name:         "mock",
+		Type:         "oidc",
+		DisplayName:  "Mock IdP",
+		Issuer:       idp.Issuer,
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	config.OAuthProviders = append(config.OAuthProviders, provider)
+	return idp, nil
+}